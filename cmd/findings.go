@@ -0,0 +1,138 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/store"
+)
+
+var (
+	queryRepo     string
+	queryScanner  string
+	querySeverity string
+	querySince    string
+	queryMinAge   string
+	queryStatus   string
+	queryJSON     bool
+)
+
+var findingsCmd = &cobra.Command{
+	Use:   "findings",
+	Short: "Inspect findings recorded in the local results store",
+}
+
+var findingsQueryCmd = &cobra.Command{
+	Use:   "query",
+	Short: "List recorded findings",
+	Example: `  # critical Trivy findings open for 30 days or more
+  eskimo findings query --scanner trivy --severity critical --min-age 30d`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if storePath == "" {
+			return fmt.Errorf("--store must be set")
+		}
+		now := time.Now().UTC()
+		q := store.Query{Org: org, Repo: queryRepo, Scanner: queryScanner, Status: queryStatus}
+		if querySeverity != "" {
+			sev, err := findings.ParseSeverity(querySeverity)
+			if err != nil {
+				return err
+			}
+			q.MinSeverity = sev
+		}
+		switch queryStatus {
+		case "", store.StatusOpen, store.StatusFixed, store.StatusSuppressed:
+		default:
+			return fmt.Errorf("unknown status %q", queryStatus)
+		}
+		if querySince != "" {
+			since, err := parseSince(querySince, now)
+			if err != nil {
+				return err
+			}
+			q.Since = since
+		}
+		if queryMinAge != "" {
+			age, err := parseAge(queryMinAge)
+			if err != nil {
+				return err
+			}
+			q.MinAge = age
+		}
+		st, err := store.Open(storePath)
+		if err != nil {
+			return err
+		}
+		records, err := st.Query(q, now)
+		if err != nil {
+			return err
+		}
+		if queryJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			return enc.Encode(records)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "REPO\tSCANNER\tSEVERITY\tRULE\tLOCATION\tSTATUS\tOPENED\tLAST SEEN")
+		for _, r := range records {
+			loc := r.Path
+			if r.Line > 0 {
+				loc += ":" + strconv.Itoa(r.Line)
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.Repo, r.Scanner, r.Severity, r.RuleID, loc, r.Status,
+				r.OpenedAt.Format(time.DateOnly), r.LastSeen.Format(time.DateOnly))
+		}
+		return w.Flush()
+	},
+}
+
+// parseAge accepts Go durations plus a day suffix, e.g. "30d".
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil || n < 0 {
+			return 0, fmt.Errorf("invalid age %q", s)
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid age %q", s)
+	}
+	return d, nil
+}
+
+// parseSince accepts a date (2006-01-02), an RFC 3339 time or an age
+// relative to now such as "7d".
+func parseSince(s string, now time.Time) (time.Time, error) {
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	age, err := parseAge(s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid --since %q: use a date, RFC 3339 time or age such as 7d", s)
+	}
+	return now.Add(-age), nil
+}
+
+func init() {
+	findingsQueryCmd.Flags().StringVar(&queryRepo, "repo", "", "only findings of this repository")
+	findingsQueryCmd.Flags().StringVar(&queryScanner, "scanner", "", "only findings of this scanner")
+	findingsQueryCmd.Flags().StringVar(&querySeverity, "severity", "", "lowest severity to list")
+	findingsQueryCmd.Flags().StringVar(&querySince, "since", "", "only findings opened since a date, RFC 3339 time or age (e.g. 7d)")
+	findingsQueryCmd.Flags().StringVar(&queryMinAge, "min-age", "", "only findings open for at least this long (e.g. 30d)")
+	findingsQueryCmd.Flags().StringVar(&queryStatus, "status", "", "\"open\", \"fixed\" or \"suppressed\"")
+	findingsQueryCmd.Flags().BoolVar(&queryJSON, "json", false, "print JSON instead of a table")
+	findingsCmd.AddCommand(findingsQueryCmd)
+}
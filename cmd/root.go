@@ -5,22 +5,55 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"time"
 
 	"github.com/spf13/cobra"
 
 	"github.com/cybrota/eskimo/internal/auth"
 	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/findings"
 	internalgithub "github.com/cybrota/eskimo/internal/github"
 	"github.com/cybrota/eskimo/internal/orchestrator"
+	"github.com/cybrota/eskimo/internal/report"
+	"github.com/cybrota/eskimo/internal/storage"
+	"github.com/cybrota/eskimo/internal/store"
+	"github.com/cybrota/eskimo/internal/suppress"
 )
 
-const defaultClonePath = "/tmp/github-repos"
+const (
+	defaultClonePath = "/tmp/github-repos"
+	defaultStorePath = "/tmp/eskimo-store"
+	// defaultStoreMaxRuns bounds the run reports the store keeps per organization.
+	defaultStoreMaxRuns = 100
+)
 
 var (
-	org        string
-	configPath string
-	sample     bool
-	clonePath  string
+	org           string
+	configPath    string
+	sample        bool
+	clonePath     string
+	artifactsPath string
+	storePath     string
+	storeMaxRuns  int
+	htmlReport    string
+	summaryMD     string
+	summaryCSV    string
+	junitPath     string
+	failOn        string
+	ignoreFile    string
+	dedup         bool
+
+	issueMode        string
+	issueMinSeverity string
+	checkMode        string
+	checkFailOn      string
+	s3Bucket         string
+	s3Region         string
+	s3Endpoint       string
+	cloneTimeout     time.Duration
+	cloneRetries     int
+	submodules       bool
+	lfsMode          string
 )
 
 var logger = slog.New(slog.NewTextHandler(os.Stdout, nil))
@@ -29,23 +62,153 @@ var rootCmd = &cobra.Command{
 	Use:   "eskimo",
 	Short: "Pluggable security scanner",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		token := auth.LoadToken()
-		if token == "" {
-			return fmt.Errorf("GITHUB_TOKEN must be set or run 'eskimo auth'")
-		}
-		cfg, err := config.Load(configPath)
+		runner, _, err := newRunner(false)
 		if err != nil {
 			return err
 		}
-		gh := internalgithub.NewClient(token, org)
-		runner := orchestrator.NewRunner(logger, gh, cfg, orchestrator.Options{
-			ClonePath: clonePath,
-			Sample:    sample,
-		})
+		// from here on errors are run failures, not usage mistakes
+		cmd.SilenceUsage = true
 		return runner.Run(context.Background())
 	},
 }
 
+// newRunner wires the GitHub client, scanner configuration and report sinks
+// from the command line flags. A perCommit runner scans single commits, as
+// serve does, and only publishes to the GitHub issue and check sinks.
+func newRunner(perCommit bool) (*orchestrator.Runner, *internalgithub.Client, error) {
+	switch internalgithub.LFSMode(lfsMode) {
+	case internalgithub.LFSDefault, internalgithub.LFSFetch, internalgithub.LFSSkip:
+	default:
+		return nil, nil, fmt.Errorf("unknown --lfs mode %q", lfsMode)
+	}
+	token := auth.LoadToken()
+	if token == "" {
+		return nil, nil, fmt.Errorf("GITHUB_TOKEN must be set or run 'eskimo auth'")
+	}
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return nil, nil, err
+	}
+	cfg.Redactor.Add(token)
+	if allowForkPRs {
+		// fork pull requests run untrusted code next to scanner secrets
+		for _, sc := range cfg.Scanners {
+			if !sc.Sandbox.Enabled && sc.Image == "" {
+				return nil, nil, fmt.Errorf("--allow-fork-prs requires every scanner to be sandboxed or containerized, %s is neither", sc.Name)
+			}
+		}
+	}
+	gh := internalgithub.NewClient(logger, token, org)
+	var sinks []report.Sink
+	if issueMode != "" {
+		minSeverity, err := findings.ParseSeverity(issueMinSeverity)
+		if err != nil {
+			return nil, nil, err
+		}
+		sink, err := internalgithub.NewIssueSink(gh, internalgithub.IssueMode(issueMode), minSeverity)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if checkMode != "" {
+		failFrom, err := findings.ParseSeverity(checkFailOn)
+		if err != nil {
+			return nil, nil, err
+		}
+		sink, err := internalgithub.NewCheckSink(gh, internalgithub.CheckMode(checkMode), failFrom)
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	var failOnSeverity findings.Severity
+	// a single commit is not a run: run-wide reports, the store and the gate
+	// would take each one for a whole sweep of the organization
+	if !perCommit {
+		runSinks, gate, err := newRunSinks()
+		if err != nil {
+			return nil, nil, err
+		}
+		sinks = append(sinks, runSinks...)
+		failOnSeverity = gate
+	}
+	// the default file is optional, any other one must exist
+	suppressions, err := suppress.Load(ignoreFile, ignoreFile == suppress.FileName)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load suppressions: %w", err)
+	}
+	return orchestrator.NewRunner(logger, gh, cfg, orchestrator.Options{
+		ClonePath:     clonePath,
+		ArtifactsPath: artifactsPath,
+		// the S3 sink uploads the artifacts of the run
+		PublishArtifacts: s3Bucket != "" && !perCommit,
+		Sample:           sample,
+		Clone: internalgithub.CloneOptions{
+			Timeout:    cloneTimeout,
+			Retries:    cloneRetries,
+			Submodules: submodules,
+			LFS:        internalgithub.LFSMode(lfsMode),
+		},
+		Sinks:        sinks,
+		FailOn:       failOnSeverity,
+		Suppressions: suppressions,
+		Dedup:        dedup,
+	}), gh, nil
+}
+
+// newRunSinks returns the sinks reporting on whole runs and the severity
+// gate of a run.
+func newRunSinks() ([]report.Sink, findings.Severity, error) {
+	var sinks []report.Sink
+	var failOnSeverity findings.Severity
+	var err error
+	var history report.History
+	if storePath != "" {
+		st, err := store.Open(storePath)
+		if err != nil {
+			return nil, 0, err
+		}
+		st.MaxRuns = storeMaxRuns
+		sinks = append(sinks, st)
+		history = st
+	}
+	if htmlReport != "" {
+		sinks = append(sinks, report.NewHTMLSink(htmlReport, history))
+	}
+	if failOn != "" {
+		failOnSeverity, err = findings.ParseSeverity(failOn)
+		if err != nil {
+			return nil, 0, err
+		}
+		if failOnSeverity == findings.SeverityUnknown {
+			return nil, 0, fmt.Errorf("--fail-on unknown is not supported; use info to fail on any finding")
+		}
+	}
+	if junitPath != "" {
+		// without a gate, test cases fail on high and critical findings
+		junitFailOn := failOnSeverity
+		if junitFailOn == findings.SeverityUnknown {
+			junitFailOn = findings.SeverityHigh
+		}
+		sinks = append(sinks, report.NewJUnitSink(junitPath, junitFailOn))
+	}
+	if summaryMD != "" {
+		sinks = append(sinks, report.NewMarkdownSink(summaryMD))
+	}
+	if summaryCSV != "" {
+		sinks = append(sinks, report.NewCSVSink(summaryCSV))
+	}
+	if s3Bucket != "" {
+		client, err := storage.NewS3Client(storage.S3Options{Bucket: s3Bucket, Region: s3Region, Endpoint: s3Endpoint})
+		if err != nil {
+			return nil, 0, err
+		}
+		sinks = append(sinks, storage.NewS3Sink(client))
+	}
+	return sinks, failOnSeverity, nil
+}
+
 func Execute() error {
 	return rootCmd.Execute()
 }
@@ -55,6 +218,29 @@ func init() {
 	rootCmd.PersistentFlags().StringVar(&configPath, "config", "scanners.yaml", "Scanner config file")
 	rootCmd.PersistentFlags().BoolVar(&sample, "sample", false, fmt.Sprintf("run scans on at most %d repositories", orchestrator.SampleLimit))
 	rootCmd.PersistentFlags().StringVar(&clonePath, "clone-path", defaultClonePath, "directory used to store cloned repositories")
+	rootCmd.PersistentFlags().StringVar(&artifactsPath, "artifacts-path", "", "directory keeping scanner result files per run (default: discard them)")
+	rootCmd.PersistentFlags().StringVar(&storePath, "store", defaultStorePath, "directory of the local results store recording every run (empty disables it)")
+	rootCmd.PersistentFlags().IntVar(&storeMaxRuns, "store-max-runs", defaultStoreMaxRuns, "number of run reports the store keeps per organization, oldest removed first (0 keeps all)")
+	rootCmd.PersistentFlags().StringVar(&htmlReport, "html-report", "", "write a self-contained HTML report of each run to this file")
+	rootCmd.PersistentFlags().StringVar(&summaryMD, "summary-md", "", "write a Markdown summary table of each run to this file")
+	rootCmd.PersistentFlags().StringVar(&summaryCSV, "summary-csv", "", "write the findings of each run as CSV to this file")
+	rootCmd.PersistentFlags().StringVar(&junitPath, "junit", "", "write JUnit XML to this file: one test suite per repository, one test case per scanner")
+	rootCmd.PersistentFlags().StringVar(&failOn, "fail-on", "", "exit non-zero when findings reach this severity or a scan fails; also the JUnit failure threshold (default high)")
+	rootCmd.PersistentFlags().StringVar(&ignoreFile, "ignore-file", suppress.FileName, "central suppression file; each repository may add its own "+suppress.FileName)
+	rootCmd.PersistentFlags().BoolVar(&dedup, "dedup", true, "merge findings of different scanners that describe the same issue (same location and CVE, CWE or similar rule)")
+	rootCmd.PersistentFlags().DurationVar(&cloneTimeout, "clone-timeout", 10*time.Minute, "maximum duration of a single clone attempt (0 disables the timeout)")
+	rootCmd.PersistentFlags().IntVar(&cloneRetries, "clone-retries", 2, "number of retries for clones that fail with transient network errors")
+	rootCmd.PersistentFlags().BoolVar(&submodules, "submodules", false, "clone submodules recursively with the same credentials")
+	rootCmd.PersistentFlags().StringVar(&lfsMode, "lfs", "", "Git LFS handling: \"fetch\" downloads objects, \"skip\" removes pointer files (default: git configuration)")
+	rootCmd.PersistentFlags().StringVar(&issueMode, "issues", "", "maintain GitHub issues for findings: \"repo\" (one per repository) or \"finding\" (one per finding)")
+	rootCmd.PersistentFlags().StringVar(&issueMinSeverity, "issues-min-severity", "medium", "lowest finding severity tracked in GitHub issues")
+	rootCmd.PersistentFlags().StringVar(&checkMode, "checks", "", "publish scanner verdicts on scanned commits: \"check-run\" (GitHub App token) or \"status\"")
+	rootCmd.PersistentFlags().StringVar(&s3Bucket, "s3-bucket", "", "upload the run report, merged SARIF and artifacts to this bucket")
+	rootCmd.PersistentFlags().StringVar(&s3Region, "s3-region", "", "bucket region (default: AWS_REGION, then us-east-1)")
+	rootCmd.PersistentFlags().StringVar(&s3Endpoint, "s3-endpoint", "", "S3-compatible endpoint such as http://minio:9000 (path-style addressing)")
+	rootCmd.PersistentFlags().StringVar(&checkFailOn, "checks-fail-on", "high", "lowest finding severity that fails a check")
 	rootCmd.MarkPersistentFlagRequired("org")
 	rootCmd.AddCommand(authCmd)
+	rootCmd.AddCommand(serveCmd)
+	rootCmd.AddCommand(findingsCmd)
 }
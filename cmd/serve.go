@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/cybrota/eskimo/internal/orchestrator"
+	"github.com/cybrota/eskimo/internal/webhook"
+)
+
+var (
+	serveAddr      string
+	serveQueueSize int
+	allowForkPRs   bool
+)
+
+// runFlags configure reports on whole runs, which serve does not publish.
+var runFlags = []string{"store", "html-report", "summary-md", "summary-csv", "junit", "s3-bucket", "fail-on"}
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Scan repositories on GitHub push and pull request webhooks",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		secret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+		if secret == "" {
+			return fmt.Errorf("GITHUB_WEBHOOK_SECRET must be set")
+		}
+		for _, name := range runFlags {
+			if cmd.Flag(name).Changed {
+				logger.Warn("flag ignored by serve, which reports single commits", slog.String("flag", "--"+name))
+			}
+		}
+		runner, gh, err := newRunner(true)
+		if err != nil {
+			return err
+		}
+		scan := func(ctx context.Context, ev webhook.Event) error {
+			repo, err := gh.GetRepo(ctx, ev.Repo)
+			if err != nil {
+				return err
+			}
+			return runner.RunTargets(ctx, []orchestrator.Target{{Repo: repo, SHA: ev.SHA, Ref: ev.Ref}})
+		}
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		defer stop()
+
+		hooks := webhook.NewServer(logger, []byte(secret), org, serveQueueSize, scan)
+		hooks.AllowForks = allowForkPRs
+		go hooks.Work(ctx)
+
+		mux := http.NewServeMux()
+		mux.Handle("/webhook", hooks)
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusOK)
+		})
+		srv := &http.Server{Addr: serveAddr, Handler: mux, ReadHeaderTimeout: 10 * time.Second, ReadTimeout: time.Minute}
+		go func() {
+			<-ctx.Done()
+			shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+			srv.Shutdown(shutdownCtx)
+		}()
+
+		logger.Info("listening for webhooks", slog.String("addr", serveAddr))
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	},
+}
+
+func init() {
+	serveCmd.Flags().StringVar(&serveAddr, "addr", ":8080", "address to listen on for GitHub webhooks")
+	serveCmd.Flags().IntVar(&serveQueueSize, "queue-size", 100, "maximum number of pending scans")
+	serveCmd.Flags().BoolVar(&allowForkPRs, "allow-fork-prs", false, "scan pull requests from forks; requires every scanner to set sandbox or image")
+}
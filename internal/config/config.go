@@ -1,20 +1,315 @@
 package config
 
 import (
-	"gopkg.in/yaml.v3"
+	"fmt"
+	"io"
 	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/secrets"
 )
 
+// FormatSARIF marks a scanner whose stdout is a SARIF document.
+const FormatSARIF = "sarif"
+
 type Scanner struct {
-	Name       string   `yaml:"name"`
+	Name string `yaml:"name"`
+	// PreCommand is shorthand for a setup hook that aborts the run on failure.
 	PreCommand []string `yaml:"pre_command"`
+	Hooks      Hooks    `yaml:"hooks"`
 	Command    []string `yaml:"command"`
-	EnvVars    []string `yaml:"env"`
-	Disable    bool     `yaml:"disable"`
+	EnvVars    EnvList  `yaml:"env"`
+	Format     string   `yaml:"format"`
+	// Severity adjusts the severities of the scanner's findings before they
+	// are reported or gated on.
+	Severity SeverityPolicy `yaml:"severity"`
+	// Outputs are globs, relative to the repository, of result files the
+	// scanner writes into the working tree. Matching files are kept as
+	// artifacts next to whatever the scanner writes to {{.OutputDir}}.
+	Outputs []string `yaml:"outputs"`
+	// NeedsHistory makes eskimo fetch commit history (bounded by
+	// HistorySince, e.g. "90 days ago") instead of a shallow checkout.
+	NeedsHistory bool   `yaml:"needs_history"`
+	HistorySince string `yaml:"history_since"`
+	// Image runs the command inside this container image using Runtime
+	// (docker or podman) with the repository mounted read-only.
+	Image   string  `yaml:"image"`
+	Runtime string  `yaml:"runtime"`
+	Sandbox Sandbox `yaml:"sandbox"`
+	// InheritEnv controls whether the scanner starts from eskimo's full
+	// environment. When false it only sees the variables listed in env.
+	InheritEnv *bool `yaml:"inherit_env"`
+	Disable    bool  `yaml:"disable"`
+	// Secrets holds env entries whose value was a secret reference, resolved
+	// by Load. They are kept apart from EnvVars so they are never expanded.
+	Secrets map[string]string `yaml:"-"`
+}
+
+// TemplateVars are the variables available to scanner command arguments,
+// e.g. ["semgrep", "--json", "-o", "{{.OutputDir}}/{{.Repo}}.json"]. Inside
+// containers Path and OutputDir refer to the mounted directories.
+type TemplateVars struct {
+	Repo          string
+	Org           string
+	Path          string
+	SHA           string
+	DefaultBranch string
+	OutputDir     string
+	Language      string
+}
+
+// ParseArg parses a command argument as a template.
+func ParseArg(arg string) (*template.Template, error) {
+	return template.New("arg").Option("missingkey=error").Parse(arg)
+}
+
+// validateArgs rejects arguments that are not valid templates or reference
+// unknown variables.
+func validateArgs(argv []string) error {
+	for _, arg := range argv {
+		tmpl, err := ParseArg(arg)
+		if err != nil {
+			return err
+		}
+		if err := tmpl.Execute(io.Discard, TemplateVars{}); err != nil {
+			return fmt.Errorf("argument %q: %w", arg, err)
+		}
+	}
+	return nil
+}
+
+// EnvList holds scanner environment entries. An entry is either a variable
+// name copied from eskimo's environment, KEY=value where ${NAME} references
+// in value are expanded from eskimo's environment (other $ signs are kept
+// literally), or KEY=<secret reference>
+// (see secrets.Resolve). In YAML it may be written as a list or as a
+// KEY: value mapping.
+type EnvList []string
+
+func (e *EnvList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind != yaml.MappingNode {
+		var list []string
+		if err := node.Decode(&list); err != nil {
+			return err
+		}
+		*e = list
+		return nil
+	}
+	list := make([]string, 0, len(node.Content)/2)
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		var key, val string
+		if err := node.Content[i].Decode(&key); err != nil {
+			return err
+		}
+		if err := node.Content[i+1].Decode(&val); err != nil {
+			return err
+		}
+		list = append(list, key+"="+val)
+	}
+	*e = list
+	return nil
+}
+
+var envNameRe = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// scannerNameRe restricts scanner names to safe path components, since each
+// scanner gets an output directory named after it.
+var scannerNameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]*$`)
+
+// Sandbox isolates a natively executed scanner on Linux. The scanner gets a
+// clean environment (a small base set, its env list and AllowEnv), its own
+// process group, resource limits and no-new-privileges.
+type Sandbox struct {
+	Enabled        bool     `yaml:"enabled"`
+	CPUSeconds     uint64   `yaml:"cpu_seconds"`
+	MemoryMB       uint64   `yaml:"memory_mb"`
+	FileSizeMB     uint64   `yaml:"file_size_mb"`
+	DisableNetwork bool     `yaml:"disable_network"`
+	AllowEnv       []string `yaml:"allow_env"`
+}
+
+// Hook failure policies.
+const (
+	// OnFailureAbort stops the whole run.
+	OnFailureAbort = "abort"
+	// OnFailureSkipScanner skips the scanner for the run (setup) or for the
+	// repository (before_repo).
+	OnFailureSkipScanner = "skip_scanner"
+	// OnFailureIgnore logs the failure and carries on.
+	OnFailureIgnore = "ignore"
+)
+
+// Hook is a command run at one point of a scanner's lifecycle.
+type Hook struct {
+	Command   []string `yaml:"command"`
+	OnFailure string   `yaml:"on_failure"`
+}
+
+// Hooks are the lifecycle commands of a scanner. Setup and Teardown run once
+// per run in the clone base directory; BeforeRepo and AfterRepo run in every
+// repository the scanner is run against, before any and after all of the
+// repository's scanners.
+type Hooks struct {
+	Setup      Hook `yaml:"setup"`
+	BeforeRepo Hook `yaml:"before_repo"`
+	AfterRepo  Hook `yaml:"after_repo"`
+	Teardown   Hook `yaml:"teardown"`
+}
+
+// normalizeHooks folds pre_command into the setup hook and fills in the
+// default failure policy of every hook.
+func (sc *Scanner) normalizeHooks() error {
+	if len(sc.PreCommand) > 0 {
+		if len(sc.Hooks.Setup.Command) > 0 {
+			return fmt.Errorf("pre_command cannot be combined with hooks.setup")
+		}
+		sc.Hooks.Setup = Hook{Command: sc.PreCommand, OnFailure: OnFailureAbort}
+		sc.PreCommand = nil
+	}
+	for _, h := range []struct {
+		name string
+		hook *Hook
+		def  string
+	}{
+		{"setup", &sc.Hooks.Setup, OnFailureAbort},
+		{"before_repo", &sc.Hooks.BeforeRepo, OnFailureSkipScanner},
+		{"after_repo", &sc.Hooks.AfterRepo, OnFailureIgnore},
+		{"teardown", &sc.Hooks.Teardown, OnFailureIgnore},
+	} {
+		switch h.hook.OnFailure {
+		case "":
+			h.hook.OnFailure = h.def
+		case OnFailureAbort, OnFailureSkipScanner, OnFailureIgnore:
+		default:
+			return fmt.Errorf("hooks.%s: unknown on_failure %q", h.name, h.hook.OnFailure)
+		}
+	}
+	return nil
+}
+
+// SeverityPolicy maps a scanner's findings onto the common severity scale.
+// Levels maps native severity labels, matched case-insensitively, and
+// Overrides set the severity of rules whose ID matches the Rule glob, e.g. to
+// demote a noisy rule or promote CVEs with known exploits. The first matching
+// override wins over Levels.
+type SeverityPolicy struct {
+	Levels    map[string]findings.Severity `yaml:"levels"`
+	Overrides []SeverityOverride           `yaml:"overrides"`
+}
+
+// SeverityOverride sets the severity of the rules matching Rule.
+type SeverityOverride struct {
+	Rule     string            `yaml:"rule"`
+	Severity findings.Severity `yaml:"severity"`
+}
+
+func (p *SeverityPolicy) normalize() error {
+	levels := make(map[string]findings.Severity, len(p.Levels))
+	for label, sev := range p.Levels {
+		levels[strings.ToLower(label)] = sev
+	}
+	p.Levels = levels
+	for _, o := range p.Overrides {
+		if _, err := path.Match(o.Rule, ""); err != nil || o.Rule == "" {
+			return fmt.Errorf("severity: invalid override rule %q", o.Rule)
+		}
+	}
+	return nil
+}
+
+// Apply sets the severity of each finding according to the policy.
+func (p SeverityPolicy) Apply(list []findings.Finding) {
+	for i := range list {
+		f := &list[i]
+		if sev, ok := p.Levels[strings.ToLower(f.Level)]; ok && f.Level != "" {
+			f.Severity = sev
+		}
+		for _, o := range p.Overrides {
+			if ok, _ := path.Match(o.Rule, f.RuleID); ok {
+				f.Severity = o.Severity
+				break
+			}
+		}
+	}
+}
+
+// Repository policies.
+const (
+	PolicySkip = "skip"
+	PolicyScan = "scan"
+)
+
+// RepoPolicy decides whether repositories in special states are scanned and
+// how matching repositories are cloned. Locked repositories have no policy:
+// the API does not report the lock, so they are only recognized, and skipped,
+// when their clone is refused.
+type RepoPolicy struct {
+	Archived string     `yaml:"archived"`
+	Disabled string     `yaml:"disabled"`
+	Empty    string     `yaml:"empty"`
+	Rules    []RepoRule `yaml:"rules"`
+}
+
+// RepoRule overrides clone settings for repositories whose name matches the
+// Match glob. Rules are applied in order; later matches win.
+type RepoRule struct {
+	Match      string `yaml:"match"`
+	Submodules *bool  `yaml:"submodules"`
+	LFS        string `yaml:"lfs"`
+	// Strategy is one of shallow, partial or sparse.
+	Strategy    string   `yaml:"strategy"`
+	SparsePaths []string `yaml:"sparse_paths"`
+	// Repositories larger than MaxSizeMB are skipped or sparsely cloned
+	// depending on Oversize.
+	MaxSizeMB int    `yaml:"max_size_mb"`
+	Oversize  string `yaml:"oversize"`
+}
+
+// Matches reports whether the rule applies to the repository name.
+func (r RepoRule) Matches(name string) bool {
+	ok, _ := filepath.Match(r.Match, name)
+	return ok
+}
+
+// OwnerPolicy attributes findings of paths no CODEOWNERS rule covers. The
+// repository custom property named Property is tried first, then a topic
+// starting with TopicPrefix (team-payments names the @org/payments team) and
+// finally the first Repos rule matching the repository name.
+type OwnerPolicy struct {
+	Property    string      `yaml:"property"`
+	TopicPrefix string      `yaml:"topic_prefix"`
+	Repos       []OwnerRule `yaml:"repos"`
+}
+
+// OwnerRule assigns Owner to repositories whose name matches the Match glob.
+type OwnerRule struct {
+	Match string `yaml:"match"`
+	Owner string `yaml:"owner"`
+}
+
+// RepoOwner returns the owner of the first rule matching the repository name.
+func (p OwnerPolicy) RepoOwner(name string) string {
+	for _, rule := range p.Repos {
+		if ok, _ := filepath.Match(rule.Match, name); ok {
+			return rule.Owner
+		}
+	}
+	return ""
 }
 
 type Config struct {
-	Scanners []Scanner `yaml:"scanners"`
+	Scanners     []Scanner   `yaml:"scanners"`
+	Repositories RepoPolicy  `yaml:"repositories"`
+	Owners       OwnerPolicy `yaml:"owners"`
+	// Redactor masks resolved secret values in logs and reports.
+	Redactor *secrets.Redactor `yaml:"-"`
 }
 
 func Load(path string) (*Config, error) {
@@ -26,13 +321,113 @@ func Load(path string) (*Config, error) {
 	if err := yaml.Unmarshal(data, &cfg); err != nil {
 		return nil, err
 	}
+	cfg.Redactor = secrets.NewRedactor()
 	var active []Scanner
+	names := make(map[string]bool)
 	for _, sc := range cfg.Scanners {
 		if sc.Disable {
 			continue
 		}
+		if !scannerNameRe.MatchString(sc.Name) {
+			return nil, fmt.Errorf("invalid scanner name %q", sc.Name)
+		}
+		if names[sc.Name] {
+			return nil, fmt.Errorf("duplicate scanner name %q", sc.Name)
+		}
+		names[sc.Name] = true
+		var env EnvList
+		for _, entry := range sc.EnvVars {
+			name, value, hasValue := strings.Cut(entry, "=")
+			if !envNameRe.MatchString(name) {
+				return nil, fmt.Errorf("scanner %s: invalid env entry %q", sc.Name, entry)
+			}
+			if !hasValue {
+				env = append(env, entry)
+				continue
+			}
+			secret, isRef, err := secrets.Resolve(value)
+			if err != nil {
+				return nil, fmt.Errorf("scanner %s: env %s: %w", sc.Name, name, err)
+			}
+			if !isRef {
+				env = append(env, entry)
+				continue
+			}
+			if sc.Secrets == nil {
+				sc.Secrets = make(map[string]string)
+			}
+			sc.Secrets[name] = secret
+			cfg.Redactor.Add(secret)
+		}
+		sc.EnvVars = env
+		if sc.Sandbox.Enabled && sc.Image != "" {
+			return nil, fmt.Errorf("scanner %s: sandbox cannot be combined with image", sc.Name)
+		}
+		switch sc.Runtime {
+		case "", "docker", "podman":
+		default:
+			return nil, fmt.Errorf("scanner %s: unsupported container runtime %q", sc.Name, sc.Runtime)
+		}
+		if sc.Format != "" && sc.Format != FormatSARIF {
+			return nil, fmt.Errorf("scanner %s: unsupported format %q", sc.Name, sc.Format)
+		}
+		if err := validateArgs(sc.Command); err != nil {
+			return nil, fmt.Errorf("scanner %s: command: %w", sc.Name, err)
+		}
+		for _, pattern := range sc.Outputs {
+			if _, err := filepath.Match(pattern, ""); err != nil || filepath.IsAbs(pattern) || strings.HasPrefix(filepath.Clean(pattern), "..") {
+				return nil, fmt.Errorf("scanner %s: invalid outputs pattern %q", sc.Name, pattern)
+			}
+		}
+		if err := sc.normalizeHooks(); err != nil {
+			return nil, fmt.Errorf("scanner %s: %w", sc.Name, err)
+		}
+		if err := sc.Severity.normalize(); err != nil {
+			return nil, fmt.Errorf("scanner %s: %w", sc.Name, err)
+		}
 		active = append(active, sc)
 	}
 	cfg.Scanners = active
+	for _, p := range []*string{&cfg.Repositories.Archived, &cfg.Repositories.Disabled, &cfg.Repositories.Empty} {
+		if *p == "" {
+			*p = PolicySkip
+		}
+		if *p != PolicySkip && *p != PolicyScan {
+			return nil, fmt.Errorf("repositories: unknown policy %q", *p)
+		}
+	}
+	for _, rule := range cfg.Repositories.Rules {
+		if _, err := filepath.Match(rule.Match, ""); err != nil || rule.Match == "" {
+			return nil, fmt.Errorf("repositories: invalid rule match %q", rule.Match)
+		}
+		switch rule.LFS {
+		case "", "fetch", "skip":
+		default:
+			return nil, fmt.Errorf("repositories: rule %q: unknown lfs mode %q", rule.Match, rule.LFS)
+		}
+		switch rule.Strategy {
+		case "", "shallow", "partial":
+		case "sparse":
+			if len(rule.SparsePaths) == 0 {
+				return nil, fmt.Errorf("repositories: rule %q: sparse strategy requires sparse_paths", rule.Match)
+			}
+		default:
+			return nil, fmt.Errorf("repositories: rule %q: unknown strategy %q", rule.Match, rule.Strategy)
+		}
+		switch rule.Oversize {
+		case "", PolicySkip:
+		case "sparse":
+			if len(rule.SparsePaths) == 0 {
+				return nil, fmt.Errorf("repositories: rule %q: oversize sparse requires sparse_paths", rule.Match)
+			}
+		default:
+			return nil, fmt.Errorf("repositories: rule %q: unknown oversize action %q", rule.Match, rule.Oversize)
+		}
+	}
+	for _, rule := range cfg.Owners.Repos {
+		if _, err := filepath.Match(rule.Match, ""); err != nil || rule.Match == "" || rule.Owner == "" {
+			return nil, fmt.Errorf("owners: invalid rule %q: %q", rule.Match, rule.Owner)
+		}
+	}
 	return &cfg, nil
 }
@@ -2,7 +2,13 @@ package config
 
 import (
 	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cybrota/eskimo/internal/findings"
 )
 
 func TestLoad(t *testing.T) {
@@ -83,3 +89,298 @@ func TestLoadDisableFalse(t *testing.T) {
 		t.Fatalf("scanner should not be disabled")
 	}
 }
+
+func TestLoadRepoPolicy(t *testing.T) {
+	data := []byte(`scanners:
+  - name: test
+    command: ["echo", "hello"]
+repositories:
+  archived: scan
+`)
+	tmp, err := os.CreateTemp("", "cfg-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	cfg, err := Load(tmp.Name())
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if cfg.Repositories.Archived != PolicyScan {
+		t.Errorf("expected archived policy scan, got %q", cfg.Repositories.Archived)
+	}
+	if cfg.Repositories.Empty != PolicySkip || cfg.Repositories.Disabled != PolicySkip {
+		t.Errorf("expected skip defaults, got %+v", cfg.Repositories)
+	}
+}
+
+func TestLoadRejectsSparseWithoutPaths(t *testing.T) {
+	data := []byte(`scanners:
+  - name: test
+    command: ["echo", "hello"]
+repositories:
+  rules:
+    - match: "mono*"
+      strategy: sparse
+`)
+	tmp, err := os.CreateTemp("", "cfg-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	if _, err := Load(tmp.Name()); err == nil {
+		t.Fatalf("expected error for sparse rule without paths")
+	}
+}
+
+func TestLoadEnvForms(t *testing.T) {
+	data := []byte(`scanners:
+  - name: list
+    command: ["echo"]
+    env: ["A", "B=literal", "C=${HOME}"]
+  - name: map
+    command: ["echo"]
+    inherit_env: false
+    env:
+      TOKEN: ${WIZ_TOKEN}
+      MODE: ci
+`)
+	tmp, err := os.CreateTemp("", "cfg-*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	tmp.Close()
+	cfg, err := Load(tmp.Name())
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	if got := strings.Join(cfg.Scanners[0].EnvVars, " "); got != "A B=literal C=${HOME}" {
+		t.Errorf("unexpected list env: %q", got)
+	}
+	m := cfg.Scanners[1]
+	if got := strings.Join(m.EnvVars, " "); got != "TOKEN=${WIZ_TOKEN} MODE=ci" {
+		t.Errorf("unexpected map env: %q", got)
+	}
+	if m.InheritEnv == nil || *m.InheritEnv {
+		t.Errorf("expected inherit_env false")
+	}
+}
+
+func TestLoadSecretReferences(t *testing.T) {
+	dir := t.TempDir()
+	secretFile := filepath.Join(dir, "token")
+	os.WriteFile(secretFile, []byte("s3cr3t-value\n"), 0600)
+	data := []byte(`scanners:
+  - name: wiz
+    command: ["echo"]
+    env:
+      WIZ_CLIENT_SECRET: file:` + secretFile + `
+      MODE: ci
+`)
+	path := filepath.Join(dir, "cfg.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	sc := cfg.Scanners[0]
+	if sc.Secrets["WIZ_CLIENT_SECRET"] != "s3cr3t-value" {
+		t.Fatalf("secret not resolved: %+v", sc.Secrets)
+	}
+	if got := strings.Join(sc.EnvVars, " "); got != "MODE=ci" {
+		t.Fatalf("secret reference left in env: %q", got)
+	}
+	if got := cfg.Redactor.Redact("token s3cr3t-value"); got != "token ***" {
+		t.Fatalf("secret not redacted: %q", got)
+	}
+}
+
+func TestLoadHooks(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`scanners:
+  - name: legacy
+    command: ["echo"]
+    pre_command: ["wizcli", "auth"]
+  - name: npm
+    command: ["echo"]
+    hooks:
+      before_repo:
+        command: ["npm", "ci"]
+      after_repo:
+        command: ["rm", "-rf", "node_modules"]
+        on_failure: abort
+`)
+	path := filepath.Join(dir, "cfg.yaml")
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	legacy := cfg.Scanners[0]
+	if strings.Join(legacy.Hooks.Setup.Command, " ") != "wizcli auth" || legacy.Hooks.Setup.OnFailure != OnFailureAbort {
+		t.Errorf("pre_command not mapped to setup hook: %+v", legacy.Hooks.Setup)
+	}
+	if legacy.PreCommand != nil {
+		t.Errorf("expected pre_command to be cleared")
+	}
+	hooks := cfg.Scanners[1].Hooks
+	if hooks.BeforeRepo.OnFailure != OnFailureSkipScanner {
+		t.Errorf("unexpected before_repo policy %q", hooks.BeforeRepo.OnFailure)
+	}
+	if hooks.AfterRepo.OnFailure != OnFailureAbort {
+		t.Errorf("unexpected after_repo policy %q", hooks.AfterRepo.OnFailure)
+	}
+	if hooks.Teardown.OnFailure != OnFailureIgnore {
+		t.Errorf("unexpected teardown policy %q", hooks.Teardown.OnFailure)
+	}
+
+	bad := []byte(`scanners:
+  - name: test
+    command: ["echo"]
+    hooks:
+      setup:
+        command: ["true"]
+        on_failure: retry
+`)
+	if err := os.WriteFile(path, bad, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for unknown on_failure")
+	}
+}
+
+func TestLoadValidatesCommandTemplates(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "cfg.yaml")
+	cases := map[string]bool{
+		`["semgrep", "-o", "{{.OutputDir}}/{{.Repo}}-{{.SHA}}.json", "{{.Path}}"]`: true,
+		`["scan", "--lang", "{{.Language}}", "--branch", "{{.DefaultBranch}}"]`:    true,
+		`["scan", "{{.Repository}}"]`: false,
+		`["scan", "{{.Repo"]`:         false,
+	}
+	for command, ok := range cases {
+		data := []byte("scanners:\n  - name: test\n    command: " + command + "\n")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if ok && err != nil {
+			t.Errorf("%s: unexpected error %v", command, err)
+		}
+		if !ok && err == nil {
+			t.Errorf("%s: expected error", command)
+		}
+	}
+}
+
+func TestLoadValidatesScannerNames(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	cases := map[string]bool{
+		"  - name: trivy-fs_v0.63\n": true,
+		"  - name: ../../etc\n":      false,
+		"  - name: a/b\n":            false,
+		"  - name: \"\"\n":           false,
+		"  - name: trivy\n    command: [x]\n  - name: trivy\n": false,
+	}
+	for scanners, ok := range cases {
+		data := []byte("scanners:\n" + scanners + "    command: [\"echo\"]\n")
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			t.Fatal(err)
+		}
+		_, err := Load(path)
+		if ok && err != nil {
+			t.Errorf("%q: unexpected error %v", scanners, err)
+		}
+		if !ok && err == nil {
+			t.Errorf("%q: expected error", scanners)
+		}
+	}
+}
+
+func TestLoadSeverityPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cfg.yaml")
+	data := []byte(`scanners:
+  - name: checkov
+    command: ["checkov"]
+    severity:
+      levels:
+        INFO: low
+      overrides:
+        - rule: CKV_AWS_18
+          severity: info
+        - rule: "CVE-2021-442*"
+          severity: critical
+`)
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("load failed: %v", err)
+	}
+	list := []findings.Finding{
+		{RuleID: "R1", Level: "info", Severity: findings.SeverityInfo},
+		{RuleID: "CKV_AWS_18", Level: "HIGH", Severity: findings.SeverityHigh},
+		{RuleID: "CVE-2021-44228", Level: "HIGH", Severity: findings.SeverityHigh},
+		{RuleID: "R2", Level: "MEDIUM", Severity: findings.SeverityMedium},
+	}
+	cfg.Scanners[0].Severity.Apply(list)
+	want := []findings.Severity{findings.SeverityLow, findings.SeverityInfo, findings.SeverityCritical, findings.SeverityMedium}
+	for i, w := range want {
+		if list[i].Severity != w {
+			t.Errorf("%s: severity %s, want %s", list[i].RuleID, list[i].Severity, w)
+		}
+	}
+
+	bad := []byte("scanners:\n  - name: x\n    command: [x]\n    severity:\n      levels:\n        ERROR: severe\n")
+	if err := os.WriteFile(path, bad, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(path); err == nil {
+		t.Fatalf("expected error for unknown severity")
+	}
+}
+
+func TestLoadShippedConfig(t *testing.T) {
+	path := filepath.Join("..", "..", "scanners.yaml")
+	if _, err := Load(path); err != nil {
+		t.Fatalf("shipped scanners.yaml does not load: %v", err)
+	}
+	// disabled scanners are dropped by Load, so inspect the file itself
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var raw Config
+	if err := yaml.Unmarshal(data, &raw); err != nil {
+		t.Fatal(err)
+	}
+	for _, sc := range raw.Scanners {
+		if sc.Name != "gitleaks" {
+			continue
+		}
+		// gitleaks exits 1 when it finds leaks, which would hide them
+		if !strings.Contains(strings.Join(sc.Command, " "), "--exit-code 0") {
+			t.Errorf("gitleaks must run with --exit-code 0: %v", sc.Command)
+		}
+		return
+	}
+	t.Fatalf("gitleaks missing from shipped config")
+}
@@ -0,0 +1,204 @@
+package findings
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// lineTolerance is how many lines apart findings of different scanners may
+// be and still describe the same code.
+const lineTolerance = 3
+
+// minSimilarity is the token overlap of rule IDs and messages above which
+// findings at the same location are considered the same issue.
+const minSimilarity = 0.3
+
+// Cluster is a set of findings different scanners reported for the same
+// issue. Members index the list given to Dedup; the first is the canonical
+// finding. Reasons explain why each further member joined.
+type Cluster struct {
+	Members []int
+	Reasons []string
+}
+
+// Dedup clusters findings of different scanners that share a location and a
+// CVE, a CWE or a similar rule. At most one finding per scanner joins a
+// cluster, so distinct findings of one scanner are never merged. Clusters
+// with a single member are not returned.
+func Dedup(list []Finding) []Cluster {
+	order := make([]int, len(list))
+	for i := range order {
+		order[i] = i
+	}
+	// the most severe, best referenced finding becomes canonical
+	sort.SliceStable(order, func(i, j int) bool {
+		a, b := list[order[i]], list[order[j]]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if len(a.Refs) != len(b.Refs) {
+			return len(a.Refs) > len(b.Refs)
+		}
+		if a.Scanner != b.Scanner {
+			return a.Scanner < b.Scanner
+		}
+		return a.RuleID < b.RuleID
+	})
+	tokens := make([]map[string]bool, len(list))
+	for i, f := range list {
+		tokens[i] = ruleTokens(f)
+	}
+	var clusters []*Cluster
+	scanners := make(map[*Cluster]map[string]bool)
+	for _, i := range order {
+		f := list[i]
+		joined := false
+		for _, c := range clusters {
+			if scanners[c][f.Scanner] {
+				continue
+			}
+			for _, m := range c.Members {
+				if reason, ok := sameIssue(list[m], f, tokens[m], tokens[i]); ok {
+					c.Members = append(c.Members, i)
+					c.Reasons = append(c.Reasons, reason)
+					scanners[c][f.Scanner] = true
+					joined = true
+					break
+				}
+			}
+			if joined {
+				break
+			}
+		}
+		if !joined {
+			c := &Cluster{Members: []int{i}}
+			clusters = append(clusters, c)
+			scanners[c] = map[string]bool{f.Scanner: true}
+		}
+	}
+	var out []Cluster
+	for _, c := range clusters {
+		if len(c.Members) > 1 {
+			out = append(out, *c)
+		}
+	}
+	return out
+}
+
+// Merge returns the canonical finding of c listing every contributing
+// scanner and the references of all members.
+func Merge(list []Finding, c Cluster) Finding {
+	f := list[c.Members[0]]
+	scanners := make(map[string]bool)
+	refs := make(map[string]bool)
+	for _, m := range c.Members {
+		scanners[list[m].Scanner] = true
+		for _, ref := range list[m].Refs {
+			refs[ref] = true
+		}
+	}
+	f.Scanners = sortedKeys(scanners)
+	f.Refs = sortedKeys(refs)
+	return f
+}
+
+func sameIssue(a, b Finding, ta, tb map[string]bool) (string, bool) {
+	if a.Repo != b.Repo || a.Scanner == b.Scanner || a.Path != b.Path {
+		return "", false
+	}
+	// dependency scanners report the same CVE at different lines of a lockfile
+	for _, ref := range a.Refs {
+		if strings.HasPrefix(ref, "CVE-") && contains(b.Refs, ref) {
+			return "same " + ref, true
+		}
+	}
+	if a.Path == "" || !near(a.Line, b.Line) {
+		return "", false
+	}
+	for _, ref := range a.Refs {
+		if strings.HasPrefix(ref, "CWE-") && contains(b.Refs, ref) {
+			return fmt.Sprintf("same %s at %s", ref, location(a)), true
+		}
+	}
+	if sim := similarity(ta, tb); sim >= minSimilarity {
+		return fmt.Sprintf("similar rule (%.2f) at %s", sim, location(a)), true
+	}
+	return "", false
+}
+
+func near(a, b int) bool {
+	if a == 0 || b == 0 {
+		return a == b
+	}
+	d := a - b
+	return d >= -lineTolerance && d <= lineTolerance
+}
+
+func location(f Finding) string {
+	if f.Line > 0 {
+		return fmt.Sprintf("%s:%d", f.Path, f.Line)
+	}
+	return f.Path
+}
+
+// stopWords carry no meaning in rule IDs and messages.
+var stopWords = map[string]bool{
+	"the": true, "and": true, "for": true, "with": true, "not": true, "are": true,
+	"has": true, "have": true, "this": true, "that": true, "from": true, "into": true,
+	"ensure": true, "enabled": true, "detected": true, "found": true, "should": true,
+	"use": true, "using": true, "been": true, "can": true, "may": true, "all": true,
+	"any": true, "its": true, "was": true, "security": true, "rules": true,
+}
+
+// ruleTokens splits the rule ID and message into lower case words, dropping
+// short words, stop words and plural endings.
+func ruleTokens(f Finding) map[string]bool {
+	words := strings.FieldsFunc(strings.ToLower(f.RuleID+" "+f.Message), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	set := make(map[string]bool, len(words))
+	for _, w := range words {
+		if len(w) < 3 || stopWords[w] {
+			continue
+		}
+		if len(w) > 4 && strings.HasSuffix(w, "s") {
+			w = strings.TrimSuffix(w, "s")
+		}
+		set[w] = true
+	}
+	return set
+}
+
+// similarity is the Jaccard index of two token sets.
+func similarity(a, b map[string]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	shared := 0
+	for w := range a {
+		if b[w] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedKeys(set map[string]bool) []string {
+	out := make([]string, 0, len(set))
+	for k := range set {
+		out = append(out, k)
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,58 @@
+package findings
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDedup(t *testing.T) {
+	list := []Finding{
+		// Trivy and Checkov on the same Terraform resource
+		{Repo: "infra", Scanner: "trivy", RuleID: "AVD-AWS-0086", Severity: SeverityHigh, Path: "s3.tf", Line: 10,
+			Message: "No public access block so not blocking public acls"},
+		{Repo: "infra", Scanner: "checkov", RuleID: "CKV_AWS_53", Severity: SeverityMedium, Path: "s3.tf", Line: 9,
+			Message: "Ensure S3 bucket has block public ACLS enabled"},
+		// Semgrep and Cycode on the same secret
+		{Repo: "infra", Scanner: "semgrep", RuleID: "detected-aws-access-key-id", Severity: SeverityHigh, Path: "app.py", Line: 3,
+			Refs: []string{"CWE-798"}},
+		{Repo: "infra", Scanner: "cycode", RuleID: "aws-access-key", Severity: SeverityCritical, Path: "app.py", Line: 3,
+			Refs: []string{"CWE-798"}},
+		// the same CVE at different lines of a lockfile
+		{Repo: "infra", Scanner: "trivy", RuleID: "CVE-2021-44228", Severity: SeverityCritical, Path: "pom.xml", Line: 40,
+			Refs: []string{"CVE-2021-44228"}},
+		{Repo: "infra", Scanner: "snyk", RuleID: "SNYK-JAVA-1", Severity: SeverityCritical, Path: "pom.xml", Line: 12,
+			Refs: []string{"CVE-2021-44228", "CWE-502"}},
+		// unrelated finding in the same file, and a second Trivy finding
+		{Repo: "infra", Scanner: "checkov", RuleID: "CKV_AWS_18", Severity: SeverityLow, Path: "s3.tf", Line: 11,
+			Message: "Ensure the S3 bucket has access logging enabled"},
+		{Repo: "infra", Scanner: "trivy", RuleID: "AVD-AWS-0087", Severity: SeverityHigh, Path: "s3.tf", Line: 10,
+			Message: "No public access block so not blocking public policies"},
+	}
+	clusters := Dedup(list)
+	if len(clusters) != 3 {
+		t.Fatalf("expected 3 clusters, got %+v", clusters)
+	}
+	canonical := map[string]Cluster{}
+	for _, c := range clusters {
+		if len(c.Members) != 2 || len(c.Reasons) != 1 {
+			t.Fatalf("unexpected cluster %+v", c)
+		}
+		canonical[list[c.Members[0]].RuleID] = c
+	}
+	terraform, ok := canonical["AVD-AWS-0086"]
+	if !ok || list[terraform.Members[1]].RuleID != "CKV_AWS_53" || !strings.HasPrefix(terraform.Reasons[0], "similar rule") {
+		t.Errorf("terraform misconfiguration not merged: %+v", canonical)
+	}
+	secret, ok := canonical["aws-access-key"]
+	if !ok || secret.Reasons[0] != "same CWE-798 at app.py:3" {
+		t.Errorf("secret not merged on CWE: %+v", canonical)
+	}
+	cve, ok := canonical["SNYK-JAVA-1"]
+	if !ok || cve.Reasons[0] != "same CVE-2021-44228" {
+		t.Fatalf("CVE not merged: %+v", canonical)
+	}
+	merged := Merge(list, cve)
+	if strings.Join(merged.Scanners, ",") != "snyk,trivy" || strings.Join(merged.Refs, ",") != "CVE-2021-44228,CWE-502" {
+		t.Errorf("unexpected merged finding %+v", merged)
+	}
+}
@@ -0,0 +1,126 @@
+package findings
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Severity is the normalized severity of a finding. Higher values are more severe.
+type Severity int
+
+const (
+	SeverityUnknown Severity = iota
+	SeverityInfo
+	SeverityLow
+	SeverityMedium
+	SeverityHigh
+	SeverityCritical
+)
+
+var severityNames = []string{"unknown", "info", "low", "medium", "high", "critical"}
+
+func (s Severity) String() string {
+	if s < 0 || int(s) >= len(severityNames) {
+		return severityNames[0]
+	}
+	return severityNames[s]
+}
+
+// ParseSeverity converts a severity name such as "high" into a Severity.
+func ParseSeverity(name string) (Severity, error) {
+	n := strings.ToLower(strings.TrimSpace(name))
+	for i, s := range severityNames {
+		if n == s {
+			return Severity(i), nil
+		}
+	}
+	return SeverityUnknown, fmt.Errorf("unknown severity %q", name)
+}
+
+// levelNames maps the native severity labels of common scanners, lower
+// cased, onto the common scale: Semgrep (ERROR, WARNING, INFO), Trivy and
+// Checkov (CRITICAL to LOW, UNKNOWN), Wiz (INFORMATIONAL), Cycode and the
+// SARIF result levels.
+var levelNames = map[string]Severity{
+	"critical":      SeverityCritical,
+	"high":          SeverityHigh,
+	"error":         SeverityHigh,
+	"medium":        SeverityMedium,
+	"moderate":      SeverityMedium,
+	"warning":       SeverityMedium,
+	"low":           SeverityLow,
+	"note":          SeverityLow,
+	"info":          SeverityInfo,
+	"informational": SeverityInfo,
+	"negligible":    SeverityInfo,
+	"none":          SeverityInfo,
+	"unknown":       SeverityUnknown,
+}
+
+// NormalizeLevel converts a scanner's native severity label into a
+// Severity. It reports false for labels it does not know.
+func NormalizeLevel(label string) (Severity, bool) {
+	s, ok := levelNames[strings.ToLower(strings.TrimSpace(label))]
+	return s, ok
+}
+
+func (s Severity) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+func (s *Severity) UnmarshalText(text []byte) error {
+	v, err := ParseSeverity(string(text))
+	if err != nil {
+		return err
+	}
+	*s = v
+	return nil
+}
+
+// Finding is a single scanner result in a scanner-agnostic form.
+type Finding struct {
+	Repo     string   `json:"repo"`
+	Scanner  string   `json:"scanner"`
+	RuleID   string   `json:"rule_id"`
+	Severity Severity `json:"severity"`
+	// Level is the severity label reported by the scanner, before
+	// normalization and overrides.
+	Level       string `json:"level,omitempty"`
+	Path        string `json:"path,omitempty"`
+	Line        int    `json:"line,omitempty"`
+	Message     string `json:"message,omitempty"`
+	Fingerprint string `json:"fingerprint"`
+	// Refs are the CVE and CWE identifiers the scanner associated with the
+	// finding.
+	Refs []string `json:"refs,omitempty"`
+	// Scanners lists every scanner that reported the finding when findings
+	// of several scanners were merged into it.
+	Scanners []string `json:"scanners,omitempty"`
+	// Owner is the team or person responsible for the affected code, if known.
+	Owner string `json:"owner,omitempty"`
+}
+
+// Fingerprint returns a stable identifier for a finding. Line numbers are
+// deliberately left out so unrelated edits above a finding do not change it.
+// hint may carry a scanner-provided fingerprint and takes precedence over the message.
+func Fingerprint(f Finding, hint string) string {
+	key := f.Message
+	if hint != "" {
+		key = hint
+	}
+	sum := sha256.Sum256([]byte(strings.Join([]string{f.Repo, f.Scanner, f.RuleID, f.Path, key}, "\x00")))
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// Filter returns the findings at or above min.
+func Filter(list []Finding, min Severity) []Finding {
+	var out []Finding
+	for _, f := range list {
+		if f.Severity >= min {
+			out = append(out, f)
+		}
+	}
+	return out
+}
@@ -0,0 +1,328 @@
+package findings
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type sarifLog struct {
+	Runs []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool struct {
+		Driver struct {
+			Rules []sarifRule `json:"rules"`
+		} `json:"driver"`
+	} `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifRule struct {
+	ID                   string `json:"id"`
+	DefaultConfiguration struct {
+		Level string `json:"level"`
+	} `json:"defaultConfiguration"`
+	Properties map[string]any `json:"properties"`
+}
+
+type sarifResult struct {
+	RuleID  string `json:"ruleId"`
+	Level   string `json:"level"`
+	Message struct {
+		Text string `json:"text"`
+	} `json:"message"`
+	Locations []struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI string `json:"uri"`
+			} `json:"artifactLocation"`
+			Region struct {
+				StartLine int `json:"startLine"`
+			} `json:"region"`
+		} `json:"physicalLocation"`
+	} `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties"`
+}
+
+// ParseSARIF extracts findings from a SARIF document produced by scanner for repo.
+// Any text before the first '{' (for example progress lines) is ignored.
+func ParseSARIF(repo, scanner string, data []byte) ([]Finding, error) {
+	start := bytes.IndexByte(data, '{')
+	if start < 0 {
+		return nil, fmt.Errorf("no SARIF document in output")
+	}
+	var doc sarifLog
+	if err := json.NewDecoder(bytes.NewReader(data[start:])).Decode(&doc); err != nil {
+		return nil, fmt.Errorf("decode SARIF: %w", err)
+	}
+	var out []Finding
+	for _, run := range doc.Runs {
+		rules := make(map[string]sarifRule, len(run.Tool.Driver.Rules))
+		for _, rule := range run.Tool.Driver.Rules {
+			rules[rule.ID] = rule
+		}
+		for _, res := range run.Results {
+			rule := rules[res.RuleID]
+			f := Finding{
+				Repo:     repo,
+				Scanner:  scanner,
+				RuleID:   res.RuleID,
+				Severity: sarifSeverity(res, rule),
+				Level:    nativeLevel(res, rule),
+				Message:  res.Message.Text,
+			}
+			if len(res.Locations) > 0 {
+				loc := res.Locations[0].PhysicalLocation
+				f.Path = strings.TrimPrefix(loc.ArtifactLocation.URI, "file://")
+				f.Line = loc.Region.StartLine
+			}
+			f.Refs = references(res, rule)
+			f.Fingerprint = Fingerprint(f, partialFingerprint(res.PartialFingerprints))
+			out = append(out, f)
+		}
+	}
+	return out, nil
+}
+
+// sarifSeverity prefers a CVSS-style score, then the scanner's own severity
+// label and finally the SARIF level.
+func sarifSeverity(res sarifResult, rule sarifRule) Severity {
+	for _, props := range []map[string]any{res.Properties, rule.Properties} {
+		if s, ok := securitySeverity(props); ok {
+			return s
+		}
+	}
+	if label := propertyLevel(res, rule); label != "" {
+		s, _ := NormalizeLevel(label)
+		return s
+	}
+	level := res.Level
+	if level == "" {
+		level = rule.DefaultConfiguration.Level
+	}
+	switch level {
+	case "error":
+		return SeverityHigh
+	case "warning", "":
+		return SeverityMedium
+	case "note":
+		return SeverityLow
+	default:
+		return SeverityInfo
+	}
+}
+
+// nativeLevel returns the severity label the scanner used for the result:
+// a "severity" property or severity tag, else the SARIF level.
+func nativeLevel(res sarifResult, rule sarifRule) string {
+	if label := propertyLevel(res, rule); label != "" {
+		return label
+	}
+	if res.Level != "" {
+		return res.Level
+	}
+	return rule.DefaultConfiguration.Level
+}
+
+// propertyLevel finds a known severity label in the "severity" property or
+// the tags of the result or its rule, as written by Trivy and Checkov.
+func propertyLevel(res sarifResult, rule sarifRule) string {
+	for _, props := range []map[string]any{res.Properties, rule.Properties} {
+		if label, ok := props["severity"].(string); ok {
+			if _, known := NormalizeLevel(label); known {
+				return label
+			}
+		}
+		tags, _ := props["tags"].([]any)
+		for _, tag := range tags {
+			if label, ok := tag.(string); ok {
+				if _, known := NormalizeLevel(label); known {
+					return label
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// securitySeverity reads the CVSS-style "security-severity" property used by
+// GitHub code scanning.
+func securitySeverity(props map[string]any) (Severity, bool) {
+	raw, ok := props["security-severity"]
+	if !ok {
+		return SeverityUnknown, false
+	}
+	var score float64
+	switch v := raw.(type) {
+	case string:
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return SeverityUnknown, false
+		}
+		score = f
+	case float64:
+		score = v
+	default:
+		return SeverityUnknown, false
+	}
+	switch {
+	case score >= 9:
+		return SeverityCritical, true
+	case score >= 7:
+		return SeverityHigh, true
+	case score >= 4:
+		return SeverityMedium, true
+	case score > 0:
+		return SeverityLow, true
+	}
+	return SeverityInfo, true
+}
+
+var refRe = regexp.MustCompile(`(?i)\b(CVE-\d{4}-\d{4,}|CWE-\d+)\b`)
+
+// references collects the CVE and CWE identifiers mentioned in the rule ID,
+// the message or the properties (such as tags) of a result and its rule.
+func references(res sarifResult, rule sarifRule) []string {
+	text := res.RuleID + " " + res.Message.Text
+	for _, props := range []map[string]any{res.Properties, rule.Properties} {
+		if len(props) > 0 {
+			data, _ := json.Marshal(props)
+			text += " " + string(data)
+		}
+	}
+	seen := make(map[string]bool)
+	var refs []string
+	for _, m := range refRe.FindAllString(text, -1) {
+		ref := strings.ToUpper(m)
+		if !seen[ref] {
+			seen[ref] = true
+			refs = append(refs, ref)
+		}
+	}
+	sort.Strings(refs)
+	return refs
+}
+
+func partialFingerprint(fps map[string]string) string {
+	if len(fps) == 0 {
+		return ""
+	}
+	keys := make([]string, 0, len(fps))
+	for k := range fps {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return fps[keys[0]]
+}
+
+// sarifScores maps severities back to "security-severity" values so encoded
+// documents parse to the same severity.
+var sarifScores = map[Severity]string{
+	SeverityCritical: "9.5",
+	SeverityHigh:     "8.0",
+	SeverityMedium:   "5.5",
+	SeverityLow:      "2.0",
+	SeverityInfo:     "0.0",
+}
+
+var sarifLevels = map[Severity]string{
+	SeverityCritical: "error",
+	SeverityHigh:     "error",
+	SeverityMedium:   "warning",
+	SeverityLow:      "note",
+}
+
+// EncodeSARIF writes findings as a SARIF 2.1.0 document with one run per
+// scanner. Paths are relative to the uriBaseId named after the repository,
+// so findings of many repositories can share a document.
+func EncodeSARIF(list []Finding) ([]byte, error) {
+	type rule struct {
+		ID string `json:"id"`
+	}
+	type region struct {
+		StartLine int `json:"startLine"`
+	}
+	type location struct {
+		PhysicalLocation struct {
+			ArtifactLocation struct {
+				URI       string `json:"uri"`
+				URIBaseID string `json:"uriBaseId"`
+			} `json:"artifactLocation"`
+			Region *region `json:"region,omitempty"`
+		} `json:"physicalLocation"`
+	}
+	type result struct {
+		RuleID  string `json:"ruleId"`
+		Level   string `json:"level"`
+		Message struct {
+			Text string `json:"text"`
+		} `json:"message"`
+		Locations           []location        `json:"locations,omitempty"`
+		PartialFingerprints map[string]string `json:"partialFingerprints"`
+		Properties          map[string]string `json:"properties"`
+	}
+	type run struct {
+		Tool struct {
+			Driver struct {
+				Name  string `json:"name"`
+				Rules []rule `json:"rules"`
+			} `json:"driver"`
+		} `json:"tool"`
+		Results []result `json:"results"`
+	}
+	runs := []*run{}
+	byScanner := make(map[string]*run)
+	seenRules := make(map[string]bool)
+	for _, f := range list {
+		r, ok := byScanner[f.Scanner]
+		if !ok {
+			r = &run{Results: []result{}}
+			r.Tool.Driver.Name = f.Scanner
+			r.Tool.Driver.Rules = []rule{}
+			byScanner[f.Scanner] = r
+			runs = append(runs, r)
+		}
+		if key := f.Scanner + "\x00" + f.RuleID; !seenRules[key] {
+			seenRules[key] = true
+			r.Tool.Driver.Rules = append(r.Tool.Driver.Rules, rule{ID: f.RuleID})
+		}
+		res := result{
+			RuleID:              f.RuleID,
+			Level:               sarifLevels[f.Severity],
+			PartialFingerprints: map[string]string{"eskimo/v1": f.Fingerprint},
+			Properties:          map[string]string{"repo": f.Repo, "security-severity": sarifScores[f.Severity]},
+		}
+		if res.Level == "" {
+			res.Level = "none"
+		}
+		if f.Level != "" {
+			res.Properties["severity"] = f.Level
+		}
+		if _, ok := sarifScores[f.Severity]; !ok {
+			delete(res.Properties, "security-severity")
+		}
+		res.Message.Text = f.Message
+		if f.Path != "" {
+			var loc location
+			loc.PhysicalLocation.ArtifactLocation.URI = f.Path
+			loc.PhysicalLocation.ArtifactLocation.URIBaseID = f.Repo
+			if f.Line > 0 {
+				loc.PhysicalLocation.Region = &region{StartLine: f.Line}
+			}
+			res.Locations = []location{loc}
+		}
+		r.Results = append(r.Results, res)
+	}
+	return json.MarshalIndent(map[string]any{
+		"$schema": "https://json.schemastore.org/sarif-2.1.0.json",
+		"version": "2.1.0",
+		"runs":    runs,
+	}, "", "  ")
+}
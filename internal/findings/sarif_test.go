@@ -0,0 +1,109 @@
+package findings
+
+import "testing"
+
+const sampleSARIF = `progress: 100%
+{
+  "version": "2.1.0",
+  "runs": [{
+    "tool": {"driver": {"name": "trivy", "rules": [
+      {"id": "CVE-1", "properties": {"security-severity": "9.8"}},
+      {"id": "R2", "defaultConfiguration": {"level": "note"}}
+    ]}},
+    "results": [
+      {"ruleId": "CVE-1", "message": {"text": "bad lib"},
+       "locations": [{"physicalLocation": {"artifactLocation": {"uri": "go.mod"}, "region": {"startLine": 3}}}]},
+      {"ruleId": "R2", "message": {"text": "style"}}
+    ]
+  }]
+}`
+
+func TestParseSARIF(t *testing.T) {
+	list, err := ParseSARIF("repo", "trivy", []byte(sampleSARIF))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(list) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(list))
+	}
+	f := list[0]
+	if f.Severity != SeverityCritical || f.Path != "go.mod" || f.Line != 3 || f.RuleID != "CVE-1" {
+		t.Fatalf("unexpected finding: %+v", f)
+	}
+	if list[1].Severity != SeverityLow {
+		t.Fatalf("expected low severity from rule level, got %s", list[1].Severity)
+	}
+	if f.Fingerprint == "" || f.Fingerprint == list[1].Fingerprint {
+		t.Fatalf("fingerprints not distinct: %q %q", f.Fingerprint, list[1].Fingerprint)
+	}
+}
+
+func TestFingerprintIgnoresLine(t *testing.T) {
+	a := Finding{Repo: "r", Scanner: "s", RuleID: "x", Path: "a.go", Line: 1, Message: "m"}
+	b := a
+	b.Line = 10
+	if Fingerprint(a, "") != Fingerprint(b, "") {
+		t.Fatalf("fingerprint should not depend on line")
+	}
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := ParseSeverity("HIGH")
+	if err != nil || s != SeverityHigh {
+		t.Fatalf("got %v, %v", s, err)
+	}
+	if _, err := ParseSeverity("nope"); err == nil {
+		t.Fatalf("expected error")
+	}
+}
+
+func TestEncodeSARIFRoundTrip(t *testing.T) {
+	in := []Finding{
+		{Repo: "app", Scanner: "trivy", RuleID: "CVE-1", Severity: SeverityCritical, Path: "go.mod", Line: 3, Message: "bad lib"},
+		{Repo: "app", Scanner: "semgrep", RuleID: "R2", Severity: SeverityLow, Message: "style"},
+	}
+	for i := range in {
+		in[i].Fingerprint = Fingerprint(in[i], "")
+	}
+	data, err := EncodeSARIF(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := ParseSARIF("app", "trivy", data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("expected 2 findings, got %d", len(out))
+	}
+	if out[0].Severity != SeverityCritical || out[0].Path != "go.mod" || out[0].Line != 3 {
+		t.Errorf("unexpected first finding %+v", out[0])
+	}
+	if out[1].Severity != SeverityLow || out[1].Message != "style" {
+		t.Errorf("unexpected second finding %+v", out[1])
+	}
+}
+
+func TestParseSARIFNativeLevels(t *testing.T) {
+	doc := `{"runs": [{
+	  "tool": {"driver": {"rules": [{"id": "CKV_AWS_18", "properties": {"tags": ["terraform", "MEDIUM"]}}]}},
+	  "results": [
+	    {"ruleId": "CKV_AWS_18", "level": "error", "message": {"text": "no access logging"}},
+	    {"ruleId": "wiz-1", "properties": {"severity": "Informational"}, "message": {"text": "x"}},
+	    {"ruleId": "semgrep-1", "level": "warning", "message": {"text": "y"}}
+	  ]
+	}]}`
+	list, err := ParseSARIF("repo", "scanner", []byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		level    string
+		severity Severity
+	}{{"MEDIUM", SeverityMedium}, {"Informational", SeverityInfo}, {"warning", SeverityMedium}}
+	for i, w := range want {
+		if list[i].Level != w.level || list[i].Severity != w.severity {
+			t.Errorf("finding %d: got level %q severity %s, want %q %s", i, list[i].Level, list[i].Severity, w.level, w.severity)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// CheckMode selects how scan verdicts are published on commits.
+type CheckMode string
+
+const (
+	// CheckRuns publishes a check run per scanner. Requires a GitHub App token.
+	CheckRuns CheckMode = "check-run"
+	// CommitStatuses publishes a commit status per scanner and works with any
+	// token that has repo:status scope.
+	CommitStatuses CheckMode = "status"
+)
+
+// maxAnnotations is the number of annotations GitHub accepts per request.
+const maxAnnotations = 50
+
+// CheckSink publishes one check run or commit status per scanner on the
+// scanned commit of every repository.
+type CheckSink struct {
+	client   *Client
+	mode     CheckMode
+	failFrom findings.Severity
+}
+
+// NewCheckSink returns a sink that fails a check when a scanner reports
+// findings at or above failFrom.
+func NewCheckSink(client *Client, mode CheckMode, failFrom findings.Severity) (*CheckSink, error) {
+	if mode != CheckRuns && mode != CommitStatuses {
+		return nil, fmt.Errorf("unknown check mode %q", mode)
+	}
+	return &CheckSink{client: client, mode: mode, failFrom: failFrom}, nil
+}
+
+func (s *CheckSink) Name() string {
+	return "github-checks"
+}
+
+func (s *CheckSink) Publish(ctx context.Context, rep *report.Report) error {
+	var errs []error
+	for _, repo := range rep.Repos {
+		if repo.SHA == "" {
+			continue
+		}
+		for _, scan := range repo.Scans {
+			var err error
+			switch {
+			case s.mode == CheckRuns:
+				err = s.createCheckRun(ctx, repo, scan)
+			case scan.Error == "" && !scan.Parsed():
+				// statuses have no neutral state, and success would claim
+				// a clean result nobody checked
+			default:
+				err = s.createStatus(ctx, repo, scan)
+			}
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %s: %w", repo.Name, scan.Scanner, err))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// verdict returns the check conclusion and a one-line summary for scan.
+func (s *CheckSink) verdict(scan report.Scan) (string, string) {
+	if scan.Error != "" {
+		return "failure", "scanner failed: " + scan.Error
+	}
+	if !scan.Parsed() {
+		return "neutral", "results not parsed, see the scanner output"
+	}
+	if len(scan.Findings) == 0 {
+		return "success", "no findings"
+	}
+	counts := make(map[findings.Severity]int)
+	failing := false
+	for _, f := range scan.Findings {
+		counts[f.Severity]++
+		if f.Severity >= s.failFrom {
+			failing = true
+		}
+	}
+	var parts []string
+	for sev := findings.SeverityCritical; sev >= findings.SeverityUnknown; sev-- {
+		if counts[sev] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[sev], sev))
+		}
+	}
+	summary := fmt.Sprintf("%d finding(s): %s", len(scan.Findings), strings.Join(parts, ", "))
+	if failing {
+		return "failure", summary
+	}
+	return "neutral", summary
+}
+
+func (s *CheckSink) createCheckRun(ctx context.Context, repo *report.Repo, scan report.Scan) error {
+	conclusion, summary := s.verdict(scan)
+	now := github.Timestamp{Time: time.Now()}
+	_, _, err := s.client.client.Checks.CreateCheckRun(ctx, s.client.org, repo.Name, github.CreateCheckRunOptions{
+		Name:        "eskimo / " + scan.Scanner,
+		HeadSHA:     repo.SHA,
+		Status:      github.String("completed"),
+		Conclusion:  github.String(conclusion),
+		CompletedAt: &now,
+		Output: &github.CheckRunOutput{
+			Title:       github.String(summary),
+			Summary:     github.String(summary),
+			Annotations: annotations(scan.Findings),
+		},
+	})
+	return err
+}
+
+func (s *CheckSink) createStatus(ctx context.Context, repo *report.Repo, scan report.Scan) error {
+	conclusion, summary := s.verdict(scan)
+	state := conclusion
+	switch {
+	case scan.Error != "":
+		state = "error"
+	case conclusion == "neutral":
+		state = "success"
+	}
+	// GitHub rejects status descriptions longer than 140 characters.
+	if len(summary) > 140 {
+		summary = summary[:137] + "..."
+	}
+	_, _, err := s.client.client.Repositories.CreateStatus(ctx, s.client.org, repo.Name, repo.SHA, &github.RepoStatus{
+		State:       github.String(state),
+		Description: github.String(summary),
+		Context:     github.String("eskimo/" + scan.Scanner),
+	})
+	return err
+}
+
+// annotations returns annotations for the most severe findings that have a location.
+func annotations(list []findings.Finding) []*github.CheckRunAnnotation {
+	sorted := make([]findings.Finding, 0, len(list))
+	for _, f := range list {
+		if f.Path != "" {
+			sorted = append(sorted, f)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Severity > sorted[j].Severity })
+	if len(sorted) > maxAnnotations {
+		sorted = sorted[:maxAnnotations]
+	}
+	out := make([]*github.CheckRunAnnotation, 0, len(sorted))
+	for _, f := range sorted {
+		line := f.Line
+		if line < 1 {
+			line = 1
+		}
+		level := "notice"
+		switch {
+		case f.Severity >= findings.SeverityHigh:
+			level = "failure"
+		case f.Severity == findings.SeverityMedium:
+			level = "warning"
+		}
+		msg := f.Message
+		if msg == "" {
+			msg = f.RuleID
+		}
+		out = append(out, &github.CheckRunAnnotation{
+			Path:            github.String(f.Path),
+			StartLine:       github.Int(line),
+			EndLine:         github.Int(line),
+			AnnotationLevel: github.String(level),
+			Title:           github.String(fmt.Sprintf("[%s] %s", f.Severity, f.RuleID)),
+			Message:         github.String(msg),
+		})
+	}
+	return out
+}
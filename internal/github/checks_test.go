@@ -0,0 +1,69 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"testing"
+
+	gh "github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+func TestCheckSinkCheckRuns(t *testing.T) {
+	var mu sync.Mutex
+	var runs []gh.CreateCheckRunOptions
+	client := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/repos/acme/app/check-runs" {
+			http.NotFound(w, r)
+			return
+		}
+		var opts gh.CreateCheckRunOptions
+		json.NewDecoder(r.Body).Decode(&opts)
+		mu.Lock()
+		runs = append(runs, opts)
+		mu.Unlock()
+		w.WriteHeader(http.StatusCreated)
+		w.Write([]byte(`{}`))
+	}))
+	sink, err := NewCheckSink(client, CheckRuns, findings.SeverityHigh)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rep := &report.Report{Org: "acme"}
+	repo := rep.Repo("app")
+	repo.SHA = "abc123"
+	repo.Scans = []report.Scan{
+		{Scanner: "trivy", Format: "sarif", Findings: []findings.Finding{{RuleID: "CVE-1", Severity: findings.SeverityCritical, Path: "go.mod", Line: 3}}},
+		{Scanner: "semgrep", Format: "sarif", Findings: []findings.Finding{{RuleID: "style", Severity: findings.SeverityLow, Path: "main.go"}}},
+		{Scanner: "checkov", Format: "sarif"},
+		// JSON output is not parsed, so an empty findings list proves nothing
+		{Scanner: "scharf"},
+	}
+	if err := sink.Publish(context.Background(), rep); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(runs) != 4 {
+		t.Fatalf("expected 4 check runs, got %d", len(runs))
+	}
+	want := map[string]string{"eskimo / trivy": "failure", "eskimo / semgrep": "neutral", "eskimo / checkov": "success", "eskimo / scharf": "neutral"}
+	for _, run := range runs {
+		if run.HeadSHA != "abc123" {
+			t.Errorf("unexpected head sha %q", run.HeadSHA)
+		}
+		if got := run.GetConclusion(); got != want[run.Name] {
+			t.Errorf("%s: expected %s, got %s", run.Name, want[run.Name], got)
+		}
+	}
+	for _, run := range runs {
+		if run.Name == "eskimo / trivy" {
+			ann := run.Output.Annotations
+			if len(ann) != 1 || ann[0].GetAnnotationLevel() != "failure" || ann[0].GetStartLine() != 3 {
+				t.Fatalf("unexpected annotations: %+v", ann)
+			}
+		}
+	}
+}
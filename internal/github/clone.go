@@ -0,0 +1,346 @@
+package github
+
+import (
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// CloneFailure classifies why a repository could not be cloned.
+type CloneFailure string
+
+const (
+	CloneAuth     CloneFailure = "auth"
+	CloneNotFound CloneFailure = "not_found"
+	CloneEmpty    CloneFailure = "empty"
+	CloneLocked   CloneFailure = "locked"
+	CloneTimeout  CloneFailure = "timeout"
+	CloneDiskFull CloneFailure = "disk_full"
+	CloneNetwork  CloneFailure = "network"
+	CloneUnknown  CloneFailure = "unknown"
+)
+
+// CloneError is returned by CloneRepo with the classified failure.
+type CloneError struct {
+	Kind CloneFailure
+	Err  error
+}
+
+func (e *CloneError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Kind, e.Err)
+}
+
+func (e *CloneError) Unwrap() error {
+	return e.Err
+}
+
+// LFSMode selects how Git LFS objects are handled.
+type LFSMode string
+
+const (
+	// LFSDefault leaves LFS handling to the local git configuration.
+	LFSDefault LFSMode = ""
+	// LFSFetch downloads LFS objects so scanners see real file content.
+	LFSFetch LFSMode = "fetch"
+	// LFSSkip does not download LFS objects and removes the pointer files.
+	LFSSkip LFSMode = "skip"
+)
+
+// lfsPointerPrefix starts every Git LFS pointer file.
+const lfsPointerPrefix = "version https://git-lfs.github.com/spec/v1"
+
+// CloneStrategy selects how much of a repository is fetched.
+type CloneStrategy string
+
+const (
+	// CloneShallow fetches only the latest commit. This is the default.
+	CloneShallow CloneStrategy = "shallow"
+	// ClonePartial fetches all commits and trees but downloads file contents
+	// only for the checked out commit (blob-less partial clone).
+	ClonePartial CloneStrategy = "partial"
+	// CloneSparse fetches the latest commit and checks out only SparsePaths.
+	CloneSparse CloneStrategy = "sparse"
+)
+
+// CloneOptions controls how repositories are cloned.
+type CloneOptions struct {
+	// Timeout bounds a single clone attempt. Zero means no timeout.
+	Timeout time.Duration
+	// Retries is the number of additional attempts after transient network failures.
+	Retries int
+	// Submodules clones submodules recursively using the client's credentials.
+	Submodules bool
+	LFS        LFSMode
+	Strategy   CloneStrategy
+	// SparsePaths are gitignore-style patterns checked out by CloneSparse.
+	SparsePaths []string
+	// History fetches commit history instead of only the latest commit.
+	// HistorySince bounds it to commits after a date such as "90 days ago";
+	// empty means full history.
+	History      bool
+	HistorySince string
+}
+
+// depthArgs returns the git clone/fetch flags limiting history.
+func (o CloneOptions) depthArgs() []string {
+	switch {
+	case o.History && o.HistorySince != "":
+		return []string{"--shallow-since=" + o.HistorySince}
+	case o.History, o.Strategy == ClonePartial:
+		return nil
+	}
+	return []string{"--depth", "1"}
+}
+
+// cloneArgs returns the git clone flags for the strategy and history depth.
+func (o CloneOptions) cloneArgs() []string {
+	args := o.depthArgs()
+	switch o.Strategy {
+	case ClonePartial:
+		args = append(args, "--filter=blob:none")
+	case CloneSparse:
+		args = append(args, "--filter=blob:none", "--sparse")
+	}
+	return args
+}
+
+// cloneRetryDelay is the delay before the first retry; it doubles per attempt.
+var cloneRetryDelay = 2 * time.Second
+
+func (c *Client) CloneRepo(ctx context.Context, repo *github.Repository, baseDir string, opts CloneOptions) (string, error) {
+	if repo.Name == nil {
+		return "", fmt.Errorf("repo name is nil")
+	}
+	name := *repo.Name
+	repoURL := repo.GetCloneURL()
+	dest := filepath.Join(baseDir, name)
+
+	if _, err := os.Stat(dest); err == nil {
+		if fi, err := os.Stat(filepath.Join(dest, ".git")); err == nil && fi.IsDir() {
+			// older clones may still carry credentials in the remote URL
+			if err := c.git(ctx, opts, "-C", dest, "remote", "set-url", "origin", repoURL); err != nil {
+				return "", err
+			}
+			if err := c.git(ctx, opts, "-C", dest, "pull"); err != nil {
+				return "", err
+			}
+			return dest, c.finishClone(ctx, dest, opts)
+		}
+		// directory exists but is not a git repo
+		if err := os.RemoveAll(dest); err != nil {
+			return "", err
+		}
+	} else if !os.IsNotExist(err) {
+		return "", err
+	}
+
+	if opts.Strategy == CloneSparse && len(opts.SparsePaths) == 0 {
+		return "", fmt.Errorf("sparse clone of %s requires sparse paths", name)
+	}
+	args := append([]string{"clone"}, opts.cloneArgs()...)
+	if opts.Submodules {
+		args = append(args, "--recurse-submodules", "--shallow-submodules")
+	}
+	args = append(args, repoURL, dest)
+	delay := cloneRetryDelay
+	for attempt := 0; ; attempt++ {
+		err := c.git(ctx, opts, args...)
+		if err == nil {
+			return dest, c.finishClone(ctx, dest, opts)
+		}
+		var ce *CloneError
+		if attempt >= opts.Retries || !errors.As(err, &ce) || ce.Kind != CloneNetwork {
+			return "", err
+		}
+		if rmErr := os.RemoveAll(dest); rmErr != nil {
+			return "", err
+		}
+		select {
+		case <-ctx.Done():
+			return "", ctx.Err()
+		case <-time.After(delay):
+		}
+		delay *= 2
+	}
+}
+
+// finishClone applies the sparse checkout patterns and LFS mode to a fresh or
+// updated clone.
+func (c *Client) finishClone(ctx context.Context, dest string, opts CloneOptions) error {
+	if opts.Strategy == CloneSparse {
+		args := append([]string{"-C", dest, "sparse-checkout", "set", "--no-cone"}, opts.SparsePaths...)
+		if err := c.git(ctx, opts, args...); err != nil {
+			return err
+		}
+	}
+	switch opts.LFS {
+	case LFSFetch:
+		if err := c.git(ctx, opts, "-C", dest, "lfs", "pull"); err != nil {
+			return err
+		}
+		if opts.Submodules {
+			return c.git(ctx, opts, "-C", dest, "submodule", "foreach", "--recursive", "git lfs pull")
+		}
+	case LFSSkip:
+		return removeLFSPointers(dest)
+	}
+	return nil
+}
+
+// removeLFSPointers deletes Git LFS pointer files so scanners do not report on them.
+func removeLFSPointers(root string) error {
+	return filepath.WalkDir(root, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		// pointer files are small; anything larger is real content
+		info, err := d.Info()
+		if err != nil || !info.Mode().IsRegular() || info.Size() > 1024 {
+			return err
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(string(data), lfsPointerPrefix) {
+			return os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// githubURL prefixes the URLs the client's token is sent to.
+const githubURL = "https://github.com/"
+
+// gitEnv returns the environment for git commands run with opts. The token is
+// passed as an HTTP header configured through the environment, so it appears
+// neither in argv nor in the remote URL stored in the clone's .git/config,
+// which scanners and hooks can read.
+func (c *Client) gitEnv(opts CloneOptions) []string {
+	env := append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if opts.LFS != LFSDefault {
+		// objects are pulled explicitly after checkout, or not at all
+		env = append(env, "GIT_LFS_SKIP_SMUDGE=1")
+	}
+	var config [][2]string
+	if c.token != "" {
+		cred := base64.StdEncoding.EncodeToString([]byte("x-access-token:" + c.token))
+		config = append(config, [2]string{"http." + githubURL + ".extraheader", "AUTHORIZATION: basic " + cred})
+		if opts.Submodules {
+			// SSH submodule URLs are fetched over HTTPS with the same header
+			config = append(config, [2]string{"url." + githubURL + ".insteadOf", "git@github.com:"})
+		}
+	}
+	if len(config) > 0 {
+		env = append(env, "GIT_CONFIG_COUNT="+strconv.Itoa(len(config)))
+		for i, kv := range config {
+			env = append(env, fmt.Sprintf("GIT_CONFIG_KEY_%d=%s", i, kv[0]), fmt.Sprintf("GIT_CONFIG_VALUE_%d=%s", i, kv[1]))
+		}
+	}
+	return env
+}
+
+// git runs a git command and converts failures into a classified *CloneError.
+// The token is scrubbed from the output because git echoes remote URLs. When
+// ctx itself ends, its error is returned as is; only the expiry of
+// opts.Timeout is a CloneTimeout.
+func (c *Client) git(ctx context.Context, opts CloneOptions, args ...string) error {
+	parent := ctx
+	if opts.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, opts.Timeout)
+		defer cancel()
+	}
+	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd.Env = c.gitEnv(opts)
+	raw, err := cmd.CombinedOutput()
+	out := string(raw)
+	if c.token != "" {
+		out = strings.ReplaceAll(out, c.token, "***")
+	}
+	if err != nil {
+		if err := parent.Err(); err != nil {
+			return err
+		}
+		if ctx.Err() != nil {
+			return &CloneError{Kind: CloneTimeout, Err: fmt.Errorf("git %s timed out after %s: %w", args[0], opts.Timeout, ctx.Err())}
+		}
+		return &CloneError{Kind: classifyGitOutput(out), Err: fmt.Errorf("git %s failed: %v: %s", args[0], err, out)}
+	}
+	if strings.Contains(out, "cloned an empty repository") {
+		return &CloneError{Kind: CloneEmpty, Err: errors.New("repository has no commits")}
+	}
+	return nil
+}
+
+// tlsErrors are the transient TLS failures git reports through curl with
+// GnuTLS or OpenSSL. Certificate problems are not among them: retrying does
+// not fix those.
+var tlsErrors = []string{
+	"gnutls_handshake() failed",
+	"gnutls recv error",
+	"tls connection was non-properly terminated",
+	"ssl_error_syscall",
+	"ssl_connect_error",
+	"unexpected eof while reading",
+}
+
+func classifyGitOutput(out string) CloneFailure {
+	lower := strings.ToLower(out)
+	contains := func(subs ...string) bool {
+		for _, s := range subs {
+			if strings.Contains(lower, s) {
+				return true
+			}
+		}
+		return false
+	}
+	switch {
+	case contains("no space left on device", "disk quota exceeded"):
+		return CloneDiskFull
+	case contains("access blocked", "repository is locked", "repository is disabled", "has been disabled"):
+		return CloneLocked
+	case contains("authentication failed", "could not read username", "permission denied", "returned error: 403", "returned error: 401"):
+		return CloneAuth
+	case contains("repository not found", "does not exist", "does not appear to be a git repository", "returned error: 404"):
+		return CloneNotFound
+	case contains("could not resolve host", "connection reset", "connection refused", "connection timed out", "failed to connect",
+		"early eof", "rpc failed", "the remote end hung up", "unexpected disconnect", "returned error: 5", "operation timed out"),
+		contains(tlsErrors...):
+		return CloneNetwork
+	}
+	return CloneUnknown
+}
+
+// Checkout fetches sha into the clone at dir and checks it out. GitHub serves
+// any reachable commit, including pull request heads from forks.
+func (c *Client) Checkout(ctx context.Context, dir, sha string, opts CloneOptions) error {
+	fetch := append([]string{"-C", dir, "fetch"}, opts.depthArgs()...)
+	if err := c.git(ctx, opts, append(fetch, "origin", sha)...); err != nil {
+		return err
+	}
+	if err := c.git(ctx, opts, "-C", dir, "checkout", "--detach", "FETCH_HEAD"); err != nil {
+		return err
+	}
+	if opts.Submodules {
+		if err := c.git(ctx, opts, "-C", dir, "submodule", "update", "--init", "--recursive", "--depth", "1"); err != nil {
+			return err
+		}
+	}
+	return c.finishClone(ctx, dir, opts)
+}
@@ -3,9 +3,9 @@ package github
 import (
 	"context"
 	"fmt"
-	"os"
+	"log/slog"
 	"os/exec"
-	"path/filepath"
+	"strings"
 
 	"github.com/google/go-github/v55/github"
 	"golang.org/x/oauth2"
@@ -19,9 +19,10 @@ type Client struct {
 	token  string
 }
 
-func NewClient(token, org string) *Client {
+func NewClient(logger *slog.Logger, token, org string) *Client {
 	ts := oauth2.StaticTokenSource(&oauth2.Token{AccessToken: token})
 	tc := oauth2.NewClient(context.Background(), ts)
+	tc.Transport = newRetryTransport(tc.Transport, logger)
 	return &Client{
 		org:    org,
 		client: github.NewClient(tc),
@@ -29,6 +30,11 @@ func NewClient(token, org string) *Client {
 	}
 }
 
+// Org returns the organization the client operates on.
+func (c *Client) Org() string {
+	return c.org
+}
+
 func (c *Client) ListRepos(ctx context.Context) ([]*github.Repository, error) {
 	var all []*github.Repository
 	opt := &github.RepositoryListByOrgOptions{Type: "all", ListOptions: github.ListOptions{PerPage: 100}}
@@ -46,39 +52,53 @@ func (c *Client) ListRepos(ctx context.Context) ([]*github.Repository, error) {
 	return all, nil
 }
 
-func (c *Client) CloneRepo(repo *github.Repository, baseDir string) (string, error) {
-	if repo.Name == nil {
-		return "", fmt.Errorf("repo name is nil")
+// GetRepo fetches a single repository of the organization.
+func (c *Client) GetRepo(ctx context.Context, name string) (*github.Repository, error) {
+	repo, _, err := c.client.Repositories.Get(ctx, c.org, name)
+	if err != nil {
+		return nil, err
 	}
-	name := *repo.Name
-	repoURL := repo.GetCloneURL()
-	dest := filepath.Join(baseDir, name)
+	return repo, nil
+}
 
-	if _, err := os.Stat(dest); err == nil {
-		if fi, err := os.Stat(filepath.Join(dest, ".git")); err == nil && fi.IsDir() {
-			cmd := exec.Command("git", "-C", dest, "pull")
-			out, err := cmd.CombinedOutput()
-			if err != nil {
-				return "", fmt.Errorf("git pull failed: %v: %s", err, string(out))
-			}
-			return dest, nil
-		}
-		// directory exists but is not a git repo
-		if err := os.RemoveAll(dest); err != nil {
-			return "", err
-		}
-	} else if !os.IsNotExist(err) {
-		return "", err
+// HeadSHA returns the commit checked out in the clone at dir.
+func (c *Client) HeadSHA(dir string) (string, error) {
+	out, err := exec.Command("git", "-C", dir, "rev-parse", "HEAD").Output()
+	if err != nil {
+		return "", fmt.Errorf("git rev-parse failed: %w", err)
 	}
+	return strings.TrimSpace(string(out)), nil
+}
 
-	authURL := repoURL
-	if c.token != "" {
-		authURL = fmt.Sprintf("https://%s@%s", c.token, repoURL[len("https://"):len(repoURL)])
-	}
-	cmd := exec.Command("git", "clone", "--depth", "1", authURL, dest)
-	out, err := cmd.CombinedOutput()
+// RepoProperties returns the custom property values of a repository. Values
+// of multi-select properties are joined with spaces. The endpoint is called
+// directly as the client library predates custom properties.
+func (c *Client) RepoProperties(ctx context.Context, repo string) (map[string]string, error) {
+	req, err := c.client.NewRequest("GET", fmt.Sprintf("repos/%s/%s/properties/values", c.org, repo), nil)
 	if err != nil {
-		return "", fmt.Errorf("git clone failed: %v: %s", err, string(out))
+		return nil, err
+	}
+	var values []struct {
+		Name  string `json:"property_name"`
+		Value any    `json:"value"`
+	}
+	if _, err := c.client.Do(ctx, req, &values); err != nil {
+		return nil, err
+	}
+	props := make(map[string]string, len(values))
+	for _, v := range values {
+		switch val := v.Value.(type) {
+		case string:
+			props[v.Name] = val
+		case []any:
+			var parts []string
+			for _, item := range val {
+				if s, ok := item.(string); ok {
+					parts = append(parts, s)
+				}
+			}
+			props[v.Name] = strings.Join(parts, " ")
+		}
 	}
-	return dest, nil
+	return props, nil
 }
@@ -1,10 +1,15 @@
 package github
 
 import (
+	"context"
+	"errors"
+	"net/http"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	gh "github.com/google/go-github/v55/github"
 )
@@ -33,7 +38,7 @@ func TestCloneRepo_UpdateExisting(t *testing.T) {
 	os.Mkdir(base, 0755)
 	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String(repoDir)}
 	c := &Client{}
-	path, err := c.CloneRepo(repo, base)
+	path, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{})
 	if err != nil {
 		t.Fatalf("clone1: %v", err)
 	}
@@ -46,7 +51,7 @@ func TestCloneRepo_UpdateExisting(t *testing.T) {
 	run(repoDir, "commit", "-m", "update")
 
 	// second clone should pull
-	path2, err := c.CloneRepo(repo, base)
+	path2, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{})
 	if err != nil {
 		t.Fatalf("clone2: %v", err)
 	}
@@ -84,7 +89,7 @@ func TestCloneRepo_ReplaceNonGitDir(t *testing.T) {
 
 	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String(repoDir)}
 	c := &Client{}
-	path, err := c.CloneRepo(repo, base)
+	path, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{})
 	if err != nil {
 		t.Fatalf("clone: %v", err)
 	}
@@ -95,3 +100,248 @@ func TestCloneRepo_ReplaceNonGitDir(t *testing.T) {
 		t.Fatalf("repo not cloned: %v", err)
 	}
 }
+
+func TestCheckout(t *testing.T) {
+	tmp := t.TempDir()
+	repoDir := filepath.Join(tmp, "remote")
+	if err := os.Mkdir(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(dir string, args ...string) string {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		out, err := cmd.CombinedOutput()
+		if err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+		return strings.TrimSpace(string(out))
+	}
+	run(repoDir, "init")
+	os.WriteFile(filepath.Join(repoDir, "a.txt"), []byte("hello"), 0644)
+	run(repoDir, "add", "a.txt")
+	run(repoDir, "commit", "-m", "init")
+	run(repoDir, "checkout", "-b", "feature")
+	os.WriteFile(filepath.Join(repoDir, "b.txt"), []byte("world"), 0644)
+	run(repoDir, "add", "b.txt")
+	run(repoDir, "commit", "-m", "feature")
+	sha := run(repoDir, "rev-parse", "HEAD")
+	run(repoDir, "checkout", "-")
+
+	base := filepath.Join(tmp, "repos")
+	os.Mkdir(base, 0755)
+	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String(repoDir)}
+	c := &Client{}
+	path, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if err := c.Checkout(context.Background(), path, sha, CloneOptions{}); err != nil {
+		t.Fatalf("checkout: %v", err)
+	}
+	if got := run(path, "rev-parse", "HEAD"); got != sha {
+		t.Fatalf("expected HEAD %s, got %s", sha, got)
+	}
+}
+
+func TestCloneRepo_ClassifiesNotFound(t *testing.T) {
+	base := t.TempDir()
+	repo := &gh.Repository{Name: gh.String("missing"), CloneURL: gh.String(filepath.Join(base, "does-not-exist"))}
+	c := &Client{}
+	_, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{Retries: 2})
+	var ce *CloneError
+	if !errors.As(err, &ce) || ce.Kind != CloneNotFound {
+		t.Fatalf("expected not_found clone error, got %v", err)
+	}
+}
+
+func TestCloneRepo_Cancellation(t *testing.T) {
+	base := t.TempDir()
+	repo := &gh.Repository{Name: gh.String("app"), CloneURL: gh.String(filepath.Join(base, "does-not-exist"))}
+	c := &Client{}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	_, err := c.CloneRepo(ctx, repo, base, CloneOptions{Timeout: time.Minute})
+	var ce *CloneError
+	if !errors.Is(err, context.Canceled) || errors.As(err, &ce) {
+		t.Fatalf("expected the cancellation to be returned as is, got %v", err)
+	}
+	// only the per-attempt deadline is a clone timeout
+	_, err = c.CloneRepo(context.Background(), repo, base, CloneOptions{Timeout: time.Nanosecond})
+	if !errors.As(err, &ce) || ce.Kind != CloneTimeout {
+		t.Fatalf("expected timeout clone error, got %v", err)
+	}
+}
+
+func TestClassifyGitOutput(t *testing.T) {
+	cases := map[string]CloneFailure{
+		"fatal: Authentication failed for 'https://github.com/acme/app.git/'":                      CloneAuth,
+		"remote: Repository not found.":                                                            CloneNotFound,
+		"fatal: unable to access: Could not resolve host: github.com":                              CloneNetwork,
+		"error: RPC failed; curl 92 HTTP/2 stream 0 was not closed cleanly":                        CloneNetwork,
+		"fatal: write error: No space left on device":                                              CloneDiskFull,
+		"remote: Repository access blocked":                                                        CloneLocked,
+		"fatal: unable to access: gnutls_handshake() failed: Error in the pull function.":          CloneNetwork,
+		"fatal: unable to access: OpenSSL SSL_read: SSL_ERROR_SYSCALL, errno 104":                  CloneNetwork,
+		"fatal: unable to access: SSL certificate problem: unable to get local issuer certificate": CloneUnknown,
+		"fatal: unexpected state in acme/mtls-gateway":                                             CloneUnknown,
+		"fatal: something odd": CloneUnknown,
+	}
+	for out, want := range cases {
+		if got := classifyGitOutput(out); got != want {
+			t.Errorf("%q: expected %s, got %s", out, want, got)
+		}
+	}
+}
+
+func TestCloneRepo_SkipLFSPointers(t *testing.T) {
+	tmp := t.TempDir()
+	repoDir := filepath.Join(tmp, "remote")
+	if err := os.Mkdir(repoDir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+	}
+	run(repoDir, "init")
+	pointer := "version https://git-lfs.github.com/spec/v1\noid sha256:abc\nsize 12345\n"
+	os.WriteFile(filepath.Join(repoDir, "model.bin"), []byte(pointer), 0644)
+	os.WriteFile(filepath.Join(repoDir, "main.go"), []byte("package main"), 0644)
+	run(repoDir, "add", ".")
+	run(repoDir, "commit", "-m", "init")
+
+	base := filepath.Join(tmp, "repos")
+	os.Mkdir(base, 0755)
+	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String(repoDir)}
+	c := &Client{}
+	path, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{LFS: LFSSkip})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "model.bin")); !os.IsNotExist(err) {
+		t.Fatalf("expected LFS pointer to be removed, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "main.go")); err != nil {
+		t.Fatalf("regular file missing: %v", err)
+	}
+}
+
+func TestCloneRepo_Sparse(t *testing.T) {
+	tmp := t.TempDir()
+	repoDir := filepath.Join(tmp, "remote")
+	if err := os.MkdirAll(filepath.Join(repoDir, "services", "payments"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	os.MkdirAll(filepath.Join(repoDir, "web"), 0755)
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+	}
+	run(repoDir, "init")
+	run(repoDir, "config", "uploadpack.allowFilter", "true")
+	os.WriteFile(filepath.Join(repoDir, "services", "payments", "main.go"), []byte("package main"), 0644)
+	os.WriteFile(filepath.Join(repoDir, "web", "index.js"), []byte("x"), 0644)
+	run(repoDir, "add", ".")
+	run(repoDir, "commit", "-m", "init")
+
+	base := filepath.Join(tmp, "repos")
+	os.Mkdir(base, 0755)
+	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String("file://" + repoDir)}
+	c := &Client{}
+	path, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{Strategy: CloneSparse, SparsePaths: []string{"/services/"}})
+	if err != nil {
+		t.Fatalf("clone: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "services", "payments", "main.go")); err != nil {
+		t.Fatalf("sparse path not checked out: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(path, "web", "index.js")); !os.IsNotExist(err) {
+		t.Fatalf("expected web/ to be excluded, got %v", err)
+	}
+}
+
+func TestCloneOptionsDepthArgs(t *testing.T) {
+	cases := []struct {
+		opts CloneOptions
+		want string
+	}{
+		{CloneOptions{}, "--depth 1"},
+		{CloneOptions{History: true}, ""},
+		{CloneOptions{History: true, HistorySince: "90 days ago"}, "--shallow-since=90 days ago"},
+		{CloneOptions{Strategy: ClonePartial}, "--filter=blob:none"},
+		{CloneOptions{Strategy: CloneSparse, History: true}, "--filter=blob:none --sparse"},
+	}
+	for _, tc := range cases {
+		if got := strings.Join(tc.opts.cloneArgs(), " "); got != tc.want {
+			t.Errorf("%+v: expected %q, got %q", tc.opts, tc.want, got)
+		}
+	}
+}
+
+func TestRepoProperties(t *testing.T) {
+	c := newTestClient(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/repos/acme/app/properties/values" {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(`[{"property_name": "owner", "value": "@acme/payments"},
+			{"property_name": "teams", "value": ["@acme/a", "@acme/b"]},
+			{"property_name": "tier", "value": null}]`))
+	}))
+	props, err := c.RepoProperties(context.Background(), "app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props["owner"] != "@acme/payments" || props["teams"] != "@acme/a @acme/b" || props["tier"] != "" {
+		t.Fatalf("unexpected properties %v", props)
+	}
+}
+
+func TestCloneRepoKeepsTokenOutOfConfig(t *testing.T) {
+	tmp := t.TempDir()
+	remote := filepath.Join(tmp, "remote")
+	run := func(dir string, args ...string) {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("%v: %s", err, out)
+		}
+	}
+	os.Mkdir(remote, 0755)
+	run(remote, "init")
+	os.WriteFile(filepath.Join(remote, "a.txt"), []byte("hello"), 0644)
+	run(remote, "add", "a.txt")
+	run(remote, "commit", "-m", "init")
+
+	const token = "ghs_secrettoken"
+	c := &Client{token: token}
+	repo := &gh.Repository{Name: gh.String("remote"), CloneURL: gh.String(remote)}
+	base := filepath.Join(tmp, "repos")
+	dest, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a clone made by an older version with the token in its remote URL
+	run(dest, "remote", "set-url", "origin", "https://"+token+"@github.com/acme/remote.git")
+	if _, err := c.CloneRepo(context.Background(), repo, base, CloneOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(dest, ".git", "config"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), token) {
+		t.Fatalf("token stored in .git/config:\n%s", data)
+	}
+
+	env := strings.Join(c.gitEnv(CloneOptions{Submodules: true}), "\n")
+	if !strings.Contains(env, "GIT_CONFIG_KEY_0=http.https://github.com/.extraheader") || strings.Contains(env, token) {
+		t.Fatalf("unexpected git environment:\n%s", env)
+	}
+}
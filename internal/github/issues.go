@@ -0,0 +1,265 @@
+package github
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// IssueMode selects how findings are tracked as GitHub issues.
+type IssueMode string
+
+const (
+	// IssuePerRepo keeps a single tracking issue per repository.
+	IssuePerRepo IssueMode = "repo"
+	// IssuePerFinding opens one issue for every finding.
+	IssuePerFinding IssueMode = "finding"
+)
+
+const (
+	issueLabel     = "eskimo"
+	repoIssueTitle = "Security findings reported by eskimo"
+	// maxIssueRows keeps tracking issue bodies well below GitHub's size limit.
+	maxIssueRows = 200
+)
+
+var markerRe = regexp.MustCompile(`<!-- eskimo:([a-z]+)=([^ ]*) -->`)
+
+// IssueSink opens, updates and closes GitHub issues for findings. Issues are
+// matched to findings through hidden markers in the issue body.
+type IssueSink struct {
+	client      *Client
+	mode        IssueMode
+	minSeverity findings.Severity
+}
+
+func NewIssueSink(client *Client, mode IssueMode, minSeverity findings.Severity) (*IssueSink, error) {
+	if mode != IssuePerRepo && mode != IssuePerFinding {
+		return nil, fmt.Errorf("unknown issue mode %q", mode)
+	}
+	return &IssueSink{client: client, mode: mode, minSeverity: minSeverity}, nil
+}
+
+func (s *IssueSink) Name() string {
+	return "github-issues"
+}
+
+func (s *IssueSink) Publish(ctx context.Context, rep *report.Report) error {
+	var errs []error
+	for _, repo := range rep.Repos {
+		// a repository that failed to clone or was skipped has no findings
+		// to compare, which must not close its issues; a commit off the
+		// default branch must neither open nor close them
+		if repo.Status != report.StatusScanned || !repo.OnDefaultBranch() {
+			continue
+		}
+		if err := s.syncRepo(ctx, repo); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", repo.Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *IssueSink) syncRepo(ctx context.Context, repo *report.Repo) error {
+	found := findings.Filter(repo.Findings(), s.minSeverity)
+	existing, err := s.client.listIssues(ctx, repo.Name)
+	if err != nil {
+		return err
+	}
+	// A failed scanner produces no findings, which must not be read as "fixed".
+	canClose := !repo.Failed()
+	if s.mode == IssuePerRepo {
+		return s.syncRepoIssue(ctx, repo.Name, found, existing, canClose)
+	}
+	return s.syncFindingIssues(ctx, repo.Name, found, existing, canClose)
+}
+
+func (s *IssueSink) syncRepoIssue(ctx context.Context, repo string, found []findings.Finding, existing []*github.Issue, canClose bool) error {
+	var issue *github.Issue
+	for _, is := range existing {
+		if _, ok := markerValue(is.GetBody(), "repo"); ok {
+			issue = is
+			break
+		}
+	}
+	if len(found) == 0 {
+		if issue != nil && issue.GetState() == "open" && canClose {
+			return s.client.closeIssue(ctx, repo, issue)
+		}
+		return nil
+	}
+	digest := fingerprintDigest(found)
+	body := repoIssueBody(repo, found, digest)
+	if issue == nil {
+		return s.client.createIssue(ctx, repo, repoIssueTitle, body)
+	}
+	if prev, _ := markerValue(issue.GetBody(), "digest"); prev == digest && issue.GetState() == "open" {
+		return nil
+	}
+	_, _, err := s.client.client.Issues.Edit(ctx, s.client.org, repo, issue.GetNumber(), &github.IssueRequest{
+		Body:  github.String(body),
+		State: github.String("open"),
+	})
+	return err
+}
+
+func (s *IssueSink) syncFindingIssues(ctx context.Context, repo string, found []findings.Finding, existing []*github.Issue, canClose bool) error {
+	byFingerprint := make(map[string]*github.Issue)
+	for _, is := range existing {
+		if fp, ok := markerValue(is.GetBody(), "fingerprint"); ok {
+			byFingerprint[fp] = is
+		}
+	}
+	var errs []error
+	seen := make(map[string]bool)
+	for _, f := range found {
+		if seen[f.Fingerprint] {
+			continue
+		}
+		seen[f.Fingerprint] = true
+		issue, ok := byFingerprint[f.Fingerprint]
+		switch {
+		case !ok:
+			title := fmt.Sprintf("[%s] %s: %s", f.Severity, f.Scanner, f.RuleID)
+			errs = append(errs, s.client.createIssue(ctx, repo, title, findingIssueBody(f)))
+		case issue.GetState() != "open":
+			_, _, err := s.client.client.Issues.Edit(ctx, s.client.org, repo, issue.GetNumber(), &github.IssueRequest{State: github.String("open")})
+			errs = append(errs, err)
+		}
+	}
+	if canClose {
+		for fp, issue := range byFingerprint {
+			if !seen[fp] && issue.GetState() == "open" {
+				errs = append(errs, s.client.closeIssue(ctx, repo, issue))
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (c *Client) listIssues(ctx context.Context, repo string) ([]*github.Issue, error) {
+	var all []*github.Issue
+	opt := &github.IssueListByRepoOptions{State: "all", Labels: []string{issueLabel}, ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		issues, resp, err := c.client.Issues.ListByRepo(ctx, c.org, repo, opt)
+		if err != nil {
+			return nil, fmt.Errorf("list issues: %w", err)
+		}
+		for _, is := range issues {
+			if !is.IsPullRequest() {
+				all = append(all, is)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opt.Page = resp.NextPage
+	}
+	return all, nil
+}
+
+func (c *Client) createIssue(ctx context.Context, repo, title, body string) error {
+	_, _, err := c.client.Issues.Create(ctx, c.org, repo, &github.IssueRequest{
+		Title:  github.String(title),
+		Body:   github.String(body),
+		Labels: &[]string{issueLabel},
+	})
+	if err != nil {
+		return fmt.Errorf("create issue: %w", err)
+	}
+	return nil
+}
+
+func (c *Client) closeIssue(ctx context.Context, repo string, issue *github.Issue) error {
+	_, _, err := c.client.Issues.Edit(ctx, c.org, repo, issue.GetNumber(), &github.IssueRequest{
+		State:       github.String("closed"),
+		StateReason: github.String("completed"),
+	})
+	if err != nil {
+		return fmt.Errorf("close issue #%d: %w", issue.GetNumber(), err)
+	}
+	return nil
+}
+
+func marker(key, value string) string {
+	return fmt.Sprintf("<!-- eskimo:%s=%s -->", key, value)
+}
+
+func markerValue(body, key string) (string, bool) {
+	for _, m := range markerRe.FindAllStringSubmatch(body, -1) {
+		if m[1] == key {
+			return m[2], true
+		}
+	}
+	return "", false
+}
+
+// fingerprintDigest hashes the sorted set of fingerprints, so the marker
+// comparing tracking issue contents stays small however many findings a
+// repository has.
+func fingerprintDigest(found []findings.Finding) string {
+	set := make(map[string]bool, len(found))
+	for _, f := range found {
+		set[f.Fingerprint] = true
+	}
+	fps := make([]string, 0, len(set))
+	for fp := range set {
+		fps = append(fps, fp)
+	}
+	sort.Strings(fps)
+	sum := sha256.Sum256([]byte(strings.Join(fps, ",")))
+	return hex.EncodeToString(sum[:])
+}
+
+func location(f findings.Finding) string {
+	if f.Path == "" {
+		return "-"
+	}
+	if f.Line > 0 {
+		return fmt.Sprintf("`%s:%d`", f.Path, f.Line)
+	}
+	return fmt.Sprintf("`%s`", f.Path)
+}
+
+func repoIssueBody(repo string, found []findings.Finding, digest string) string {
+	sorted := append([]findings.Finding(nil), found...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Severity > sorted[j].Severity })
+	var b strings.Builder
+	fmt.Fprintf(&b, "eskimo found %d security finding(s) in `%s`.\n\n", len(found), repo)
+	b.WriteString("| Severity | Scanner | Rule | Location | Owner |\n|---|---|---|---|---|\n")
+	for i, f := range sorted {
+		if i == maxIssueRows {
+			fmt.Fprintf(&b, "\n_%d more finding(s) not shown._\n", len(sorted)-maxIssueRows)
+			break
+		}
+		fmt.Fprintf(&b, "| %s | %s | %s | %s | %s |\n", f.Severity, f.Scanner, f.RuleID, location(f), f.Owner)
+	}
+	b.WriteString("\nThis issue is maintained by eskimo and closes automatically once the findings are resolved.\n")
+	b.WriteString(marker("repo", repo) + "\n" + marker("digest", digest) + "\n")
+	return b.String()
+}
+
+func findingIssueBody(f findings.Finding) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "**Scanner:** %s\n**Rule:** %s\n**Severity:** %s\n**Location:** %s\n", f.Scanner, f.RuleID, f.Severity, location(f))
+	if f.Owner != "" {
+		fmt.Fprintf(&b, "**Owner:** %s\n", f.Owner)
+	}
+	b.WriteString("\n")
+	if f.Message != "" {
+		fmt.Fprintf(&b, "%s\n\n", f.Message)
+	}
+	b.WriteString("This issue is maintained by eskimo and closes automatically once the finding is resolved.\n")
+	b.WriteString(marker("fingerprint", f.Fingerprint) + "\n")
+	return b.String()
+}
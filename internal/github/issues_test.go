@@ -0,0 +1,186 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	gh "github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// fakeIssues is an in-memory stand-in for the GitHub issues API of acme/app.
+type fakeIssues struct {
+	mu     sync.Mutex
+	issues []*gh.Issue
+}
+
+func (f *fakeIssues) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/acme/app/issues":
+		json.NewEncoder(w).Encode(f.issues)
+	case r.Method == http.MethodPost && r.URL.Path == "/repos/acme/app/issues":
+		var req gh.IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		is := &gh.Issue{Number: gh.Int(len(f.issues) + 1), Title: req.Title, Body: req.Body, State: gh.String("open")}
+		f.issues = append(f.issues, is)
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(is)
+	case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, "/repos/acme/app/issues/"):
+		n, _ := strconv.Atoi(strings.TrimPrefix(r.URL.Path, "/repos/acme/app/issues/"))
+		var req gh.IssueRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		is := f.issues[n-1]
+		if req.Body != nil {
+			is.Body = req.Body
+		}
+		if req.State != nil {
+			is.State = req.State
+		}
+		json.NewEncoder(w).Encode(is)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func newTestClient(t *testing.T, h http.Handler) *Client {
+	t.Helper()
+	srv := httptest.NewServer(h)
+	t.Cleanup(srv.Close)
+	client := gh.NewClient(nil)
+	client.BaseURL, _ = url.Parse(srv.URL + "/")
+	return &Client{org: "acme", client: client}
+}
+
+func testReport(list ...findings.Finding) *report.Report {
+	rep := &report.Report{Org: "acme"}
+	repo := rep.Repo("app")
+	repo.Status = report.StatusScanned
+	repo.Scans = []report.Scan{{Scanner: "trivy", Findings: list}}
+	return rep
+}
+
+func TestIssueSinkPerFinding(t *testing.T) {
+	fake := &fakeIssues{}
+	sink, err := NewIssueSink(newTestClient(t, fake), IssuePerFinding, findings.SeverityMedium)
+	if err != nil {
+		t.Fatal(err)
+	}
+	high := findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "CVE-1", Severity: findings.SeverityHigh, Fingerprint: "aaa"}
+	low := findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "R", Severity: findings.SeverityLow, Fingerprint: "bbb"}
+	ctx := context.Background()
+
+	if err := sink.Publish(ctx, testReport(high, low)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(fake.issues) != 1 || !strings.Contains(fake.issues[0].GetBody(), "eskimo:fingerprint=aaa") {
+		t.Fatalf("expected one issue for the high finding, got %+v", fake.issues)
+	}
+	// a second run with the same finding must not create a duplicate
+	if err := sink.Publish(ctx, testReport(high)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(fake.issues) != 1 {
+		t.Fatalf("expected deduplicated issue, got %d", len(fake.issues))
+	}
+	if err := sink.Publish(ctx, testReport()); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if fake.issues[0].GetState() != "closed" {
+		t.Fatalf("expected issue to be closed once the finding is gone")
+	}
+}
+
+func TestIssueSinkPerRepo(t *testing.T) {
+	fake := &fakeIssues{}
+	sink, err := NewIssueSink(newTestClient(t, fake), IssuePerRepo, findings.SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "A", Severity: findings.SeverityHigh, Fingerprint: "aaa"}
+	b := findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "B", Severity: findings.SeverityLow, Fingerprint: "bbb"}
+	ctx := context.Background()
+
+	if err := sink.Publish(ctx, testReport(a)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if err := sink.Publish(ctx, testReport(a, b)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if len(fake.issues) != 1 {
+		t.Fatalf("expected a single tracking issue, got %d", len(fake.issues))
+	}
+	if !strings.Contains(fake.issues[0].GetBody(), "eskimo:digest="+fingerprintDigest([]findings.Finding{b, a})) {
+		t.Fatalf("issue body not updated: %s", fake.issues[0].GetBody())
+	}
+
+	many := make([]findings.Finding, 5000)
+	for i := range many {
+		many[i] = findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "A", Severity: findings.SeverityHigh, Fingerprint: fmt.Sprintf("%064x", i)}
+	}
+	if err := sink.Publish(ctx, testReport(many...)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if n := len(fake.issues[0].GetBody()); n > 65536 {
+		t.Fatalf("issue body of %d characters exceeds GitHub's limit", n)
+	}
+
+	failed := testReport()
+	failed.Repos[0].Scans[0].Error = "exit status 1"
+	if err := sink.Publish(ctx, failed); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if fake.issues[0].GetState() != "open" {
+		t.Fatalf("issue must stay open when a scanner failed")
+	}
+}
+
+func TestIssueSinkKeepsIssuesOfUnscannedRepos(t *testing.T) {
+	fake := &fakeIssues{}
+	sink, err := NewIssueSink(newTestClient(t, fake), IssuePerRepo, findings.SeverityLow)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	a := findings.Finding{Repo: "app", Scanner: "trivy", RuleID: "A", Severity: findings.SeverityHigh, Fingerprint: "aaa"}
+	if err := sink.Publish(ctx, testReport(a)); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	for _, status := range []string{report.StatusCloneFailed, report.StatusSkipped} {
+		rep := &report.Report{Org: "acme", Repos: []*report.Repo{{Name: "app", Status: status, Reason: "network"}}}
+		if err := sink.Publish(ctx, rep); err != nil {
+			t.Fatalf("publish: %v", err)
+		}
+		if len(fake.issues) != 1 || fake.issues[0].GetState() != "open" {
+			t.Fatalf("tracking issue closed for a %s repository", status)
+		}
+	}
+
+	// a pull request head without the finding must not close it either
+	pr := testReport()
+	pr.Repos[0].Ref = "refs/pull/7/head"
+	if err := sink.Publish(ctx, pr); err != nil {
+		t.Fatalf("publish: %v", err)
+	}
+	if fake.issues[0].GetState() != "open" {
+		t.Fatalf("tracking issue closed by a pull request scan")
+	}
+}
+
+func TestFindingIssueBodyOwner(t *testing.T) {
+	f := findings.Finding{Scanner: "trivy", RuleID: "CVE-1", Severity: findings.SeverityHigh, Path: "go.mod", Owner: "@acme/payments"}
+	if body := findingIssueBody(f); !strings.Contains(body, "**Owner:** @acme/payments\n") {
+		t.Fatalf("owner missing from issue body:\n%s", body)
+	}
+}
@@ -0,0 +1,241 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	defaultMaxRetries = 5
+	defaultBaseDelay  = time.Second
+	defaultMaxDelay   = time.Minute
+	// defaultMaxWait bounds how long a request may wait for a rate limit reset.
+	defaultMaxWait = 15 * time.Minute
+	// lowQuota is the remaining request count below which quota is logged as a warning.
+	lowQuota = 100
+	// secondaryDelay is the least GitHub asks clients to wait after hitting a
+	// secondary rate limit that carries no Retry-After.
+	secondaryDelay = time.Minute
+	// maxErrorBody bounds how much of an error response is read to classify it.
+	maxErrorBody = 64 << 10
+)
+
+// retryTransport retries idempotent GitHub API requests that hit primary or
+// secondary rate limits or transient server errors. It honours Retry-After and
+// X-RateLimit-Reset and otherwise backs off exponentially with full jitter.
+// Other requests, such as issue creation, are only retried when a rate limit
+// response carries Retry-After, since GitHub did not process them.
+type retryTransport struct {
+	base       http.RoundTripper
+	logger     *slog.Logger
+	maxRetries int
+	baseDelay  time.Duration
+	maxDelay   time.Duration
+	maxWait    time.Duration
+	sleep      func(ctx context.Context, d time.Duration) error
+}
+
+func newRetryTransport(base http.RoundTripper, logger *slog.Logger) *retryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &retryTransport{
+		base:       base,
+		logger:     logger,
+		maxRetries: defaultMaxRetries,
+		baseDelay:  defaultBaseDelay,
+		maxDelay:   defaultMaxDelay,
+		maxWait:    defaultMaxWait,
+		sleep:      sleepContext,
+	}
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+		resp, err := t.base.RoundTrip(req)
+		if resp != nil {
+			t.logQuota(req, resp)
+		}
+		if attempt >= t.maxRetries {
+			return resp, err
+		}
+		delay, retry := t.retryDelay(req, resp, err, attempt)
+		if !retry || delay > t.maxWait {
+			return resp, err
+		}
+		if resp != nil {
+			io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+		}
+		t.logger.Warn("retrying GitHub API request",
+			slog.String("method", req.Method),
+			slog.String("path", req.URL.Path),
+			slog.Int("attempt", attempt+1),
+			slog.Duration("delay", delay),
+			slog.Any("reason", retryReason(resp, err)))
+		if err := t.sleep(req.Context(), delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// retryDelay reports whether the outcome of req is retryable and how long to
+// wait.
+func (t *retryTransport) retryDelay(req *http.Request, resp *http.Response, err error, attempt int) (time.Duration, bool) {
+	if !idempotent(req) {
+		if err != nil || !rateLimited(resp) || !replayable(req) {
+			return 0, false
+		}
+		return retryAfter(resp)
+	}
+	if err != nil {
+		return t.backoff(attempt), true
+	}
+	switch {
+	case rateLimited(resp):
+		if d, ok := retryAfter(resp); ok {
+			return d, true
+		}
+		if resp.Header.Get("X-RateLimit-Remaining") == "0" {
+			if d, ok := untilReset(resp); ok {
+				return d, true
+			}
+		}
+		if secondaryLimit(resp) {
+			return secondaryDelay + t.backoff(attempt), true
+		}
+		// a plain 403 is a permission error, not a rate limit
+		if resp.StatusCode == http.StatusForbidden {
+			return 0, false
+		}
+		return t.backoff(attempt), true
+	case resp.StatusCode == http.StatusInternalServerError, resp.StatusCode == http.StatusBadGateway,
+		resp.StatusCode == http.StatusServiceUnavailable, resp.StatusCode == http.StatusGatewayTimeout:
+		return t.backoff(attempt), true
+	}
+	return 0, false
+}
+
+func (t *retryTransport) backoff(attempt int) time.Duration {
+	d := t.baseDelay << attempt
+	if d <= 0 || d > t.maxDelay {
+		d = t.maxDelay
+	}
+	return time.Duration(rand.Int63n(int64(d)) + 1)
+}
+
+func (t *retryTransport) logQuota(req *http.Request, resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get("X-RateLimit-Remaining"))
+	if err != nil {
+		return
+	}
+	attrs := []any{slog.Int("remaining", remaining), slog.String("limit", resp.Header.Get("X-RateLimit-Limit")), slog.String("path", req.URL.Path)}
+	if remaining < lowQuota {
+		t.logger.Warn("GitHub API quota running low", attrs...)
+		return
+	}
+	t.logger.Debug("GitHub API quota", attrs...)
+}
+
+func idempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	case http.MethodPut, http.MethodDelete:
+		return replayable(req)
+	}
+	return false
+}
+
+// replayable reports whether the body of req can be sent again.
+func replayable(req *http.Request) bool {
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+func rateLimited(resp *http.Response) bool {
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusForbidden
+}
+
+// secondaryMarkers identify secondary (formerly abuse) rate limit errors in
+// the message or documentation URL of a response body.
+var secondaryMarkers = []string{"secondary rate limit", "secondary-rate-limits", "abuse-rate-limits", "abuse detection"}
+
+// secondaryLimit reports whether resp is a secondary rate limit error, which
+// GitHub signals only in the body. The body is restored so the caller can
+// still read it.
+func secondaryLimit(resp *http.Response) bool {
+	body := resp.Body
+	data, err := io.ReadAll(io.LimitReader(body, maxErrorBody))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(data), body), body}
+	if err != nil {
+		return false
+	}
+	msg := strings.ToLower(string(data))
+	for _, marker := range secondaryMarkers {
+		if strings.Contains(msg, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+func retryAfter(resp *http.Response) (time.Duration, bool) {
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0, false
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second, true
+	}
+	if at, err := http.ParseTime(v); err == nil {
+		return time.Until(at), true
+	}
+	return 0, false
+}
+
+func untilReset(resp *http.Response) (time.Duration, bool) {
+	epoch, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64)
+	if err != nil {
+		return 0, false
+	}
+	d := time.Until(time.Unix(epoch, 0)) + time.Second
+	if d < 0 {
+		d = 0
+	}
+	return d, true
+}
+
+func retryReason(resp *http.Response, err error) any {
+	if err != nil {
+		return err
+	}
+	return resp.Status
+}
+
+func sleepContext(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,190 @@
+package github
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestTransport(delays *[]time.Duration) *retryTransport {
+	t := newRetryTransport(nil, slog.New(slog.NewTextHandler(io.Discard, nil)))
+	t.sleep = func(ctx context.Context, d time.Duration) error {
+		*delays = append(*delays, d)
+		return nil
+	}
+	return t
+}
+
+func TestRetryTransportRetriesServerErrors(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || calls != 3 || len(delays) != 2 {
+		t.Fatalf("status %d after %d calls and %d retries", resp.StatusCode, calls, len(delays))
+	}
+}
+
+func TestRetryTransportHonoursRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("Retry-After", "7")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit"}`))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if len(delays) != 1 || delays[0] != 7*time.Second {
+		t.Fatalf("expected a single 7s wait, got %v", delays)
+	}
+}
+
+func TestRetryTransportSkipsNonIdempotent(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader("{}"))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Fatalf("POST must not be retried, got %d calls", calls)
+	}
+}
+
+func TestRetryTransportPlainForbidden(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.Header().Set("X-RateLimit-Remaining", "4000")
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if calls != 1 {
+		t.Fatalf("permission errors must not be retried, got %d calls", calls)
+	}
+}
+
+func TestRetryTransportSecondaryLimitWithoutRetryAfter(t *testing.T) {
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			w.Header().Set("X-RateLimit-Remaining", "4000")
+			w.WriteHeader(http.StatusForbidden)
+			w.Write([]byte(`{"message":"You have exceeded a secondary rate limit. Please wait a few minutes before you try again.","documentation_url":"https://docs.github.com/rest/overview/rate-limits-for-the-rest-api#about-secondary-rate-limits"}`))
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || len(delays) != 1 || delays[0] < time.Minute {
+		t.Fatalf("expected one wait of at least a minute, got status %d after %v", resp.StatusCode, delays)
+	}
+}
+
+func TestRetryTransportRetriesRateLimitedPost(t *testing.T) {
+	var calls int32
+	var bodies []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(data))
+		switch atomic.AddInt32(&calls, 1) {
+		case 1:
+			w.Header().Set("Retry-After", "3")
+			w.WriteHeader(http.StatusForbidden)
+		case 2:
+			// without Retry-After GitHub may have processed the request
+			w.WriteHeader(http.StatusTooManyRequests)
+		default:
+			w.WriteHeader(http.StatusCreated)
+		}
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Post(srv.URL, "application/json", strings.NewReader(`{"title":"x"}`))
+	if err != nil {
+		t.Fatalf("post: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || calls != 2 || len(delays) != 1 || delays[0] != 3*time.Second {
+		t.Fatalf("status %d after %d calls and waits %v", resp.StatusCode, calls, delays)
+	}
+	if bodies[1] != `{"title":"x"}` {
+		t.Fatalf("retried POST sent body %q", bodies[1])
+	}
+}
+
+func TestRetryTransportKeepsErrorBody(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+	}))
+	defer srv.Close()
+
+	var delays []time.Duration
+	client := &http.Client{Transport: newTestTransport(&delays)}
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	defer resp.Body.Close()
+	data, _ := io.ReadAll(resp.Body)
+	if len(delays) != 0 || !strings.Contains(string(data), "not accessible") {
+		t.Fatalf("unexpected retries %v or body %q", delays, data)
+	}
+}
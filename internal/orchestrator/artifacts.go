@@ -0,0 +1,89 @@
+package orchestrator
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/scanner"
+)
+
+// runIDLayout formats the run start time into the run ID.
+const runIDLayout = "20060102T150405.000Z"
+
+// runDirectory creates the directory that receives the scanner outputs of a
+// run. Without an artifacts path a temporary directory is used and keep is
+// false.
+func (r *Runner) runDirectory(runID string) (dir string, keep bool, err error) {
+	if r.opts.ArtifactsPath == "" {
+		dir, err = os.MkdirTemp("", "eskimo-run-")
+		if err != nil {
+			return "", false, fmt.Errorf("create run directory: %w", err)
+		}
+		return dir, false, nil
+	}
+	base, err := filepath.Abs(r.opts.ArtifactsPath)
+	if err != nil {
+		return "", false, fmt.Errorf("resolve artifacts path: %w", err)
+	}
+	dir = filepath.Join(base, runID)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", false, fmt.Errorf("create run directory: %w", err)
+	}
+	return dir, true, nil
+}
+
+// scanFindings parses the SARIF results of a scan. SARIF files among the
+// artifacts take precedence over stdout.
+func scanFindings(repo, scannerName string, res scanner.Result, outDir string) ([]findings.Finding, error) {
+	var all []findings.Finding
+	parsed := false
+	for _, a := range res.Artifacts {
+		if !strings.HasSuffix(a, ".sarif") && !strings.HasSuffix(a, ".sarif.json") {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(outDir, filepath.FromSlash(a)))
+		if err != nil {
+			return nil, err
+		}
+		list, err := findings.ParseSARIF(repo, scannerName, data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", a, err)
+		}
+		all = append(all, list...)
+		parsed = true
+	}
+	if !parsed {
+		return findings.ParseSARIF(repo, scannerName, res.Stdout)
+	}
+	return all, nil
+}
+
+// removeEmptyDirs removes dir and its subdirectories that hold no files. It
+// reports whether dir itself was removed.
+func removeEmptyDirs(dir string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	empty := true
+	for _, e := range entries {
+		if !e.IsDir() {
+			empty = false
+			continue
+		}
+		removed, err := removeEmptyDirs(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return false, err
+		}
+		if !removed {
+			empty = false
+		}
+	}
+	if !empty {
+		return false, nil
+	}
+	return true, os.Remove(dir)
+}
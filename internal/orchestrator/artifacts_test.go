@@ -0,0 +1,50 @@
+package orchestrator
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/scanner"
+)
+
+const testSARIF = `{"runs":[{"tool":{"driver":{"name":"t"}},"results":[{"ruleId":"R1","level":"error","message":{"text":"m"},
+"locations":[{"physicalLocation":{"artifactLocation":{"uri":"a.go"},"region":{"startLine":1}}}]}]}]}`
+
+func TestScanFindingsPrefersArtifacts(t *testing.T) {
+	outDir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(outDir, "results.sarif"), []byte(testSARIF), 0644); err != nil {
+		t.Fatal(err)
+	}
+	res := scanner.Result{Stdout: []byte("not sarif"), Artifacts: []string{"log.txt", "results.sarif"}}
+	list, err := scanFindings("app", "trivy", res, outDir)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+	if len(list) != 1 || list[0].RuleID != "R1" {
+		t.Fatalf("unexpected findings %+v", list)
+	}
+
+	res = scanner.Result{Stdout: []byte(testSARIF)}
+	if list, err := scanFindings("app", "trivy", res, outDir); err != nil || len(list) != 1 {
+		t.Fatalf("expected stdout findings, got %+v, %v", list, err)
+	}
+}
+
+func TestRemoveEmptyDirs(t *testing.T) {
+	root := t.TempDir()
+	run := filepath.Join(root, "run")
+	os.MkdirAll(filepath.Join(run, "app", "semgrep"), 0755)
+	os.MkdirAll(filepath.Join(run, "lib", "trivy"), 0755)
+	os.WriteFile(filepath.Join(run, "lib", "trivy", "out.json"), []byte("{}"), 0644)
+	removed, err := removeEmptyDirs(run)
+	if err != nil || removed {
+		t.Fatalf("run dir should be kept: removed=%v err=%v", removed, err)
+	}
+	if _, err := os.Stat(filepath.Join(run, "app")); !os.IsNotExist(err) {
+		t.Fatalf("empty repo dir not removed")
+	}
+	if _, err := os.Stat(filepath.Join(run, "lib", "trivy", "out.json")); err != nil {
+		t.Fatalf("artifact removed: %v", err)
+	}
+}
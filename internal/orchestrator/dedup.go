@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// dedupRepo merges findings of different scanners that describe the same
+// issue. The canonical finding stays with its scanner and lists every
+// contributing scanner; the others move to the duplicates of their scan. The
+// decisions are recorded on the repository.
+func dedupRepo(repo *report.Repo) {
+	type position struct{ scan, index int }
+	var all []findings.Finding
+	var at []position
+	for i, scan := range repo.Scans {
+		for j, f := range scan.Findings {
+			all = append(all, f)
+			at = append(at, position{i, j})
+		}
+	}
+	clusters := findings.Dedup(all)
+	if len(clusters) == 0 {
+		return
+	}
+	duplicate := make(map[position]bool)
+	for _, c := range clusters {
+		canonical := findings.Merge(all, c)
+		head := at[c.Members[0]]
+		repo.Scans[head.scan].Findings[head.index] = canonical
+		d := report.Dedup{Canonical: canonical.Fingerprint, Scanner: canonical.Scanner, RuleID: canonical.RuleID}
+		for k, m := range c.Members[1:] {
+			duplicate[at[m]] = true
+			f := all[m]
+			d.Duplicates = append(d.Duplicates, report.DedupMember{Fingerprint: f.Fingerprint, Scanner: f.Scanner, RuleID: f.RuleID, Reason: c.Reasons[k]})
+		}
+		repo.Dedup = append(repo.Dedup, d)
+	}
+	for i := range repo.Scans {
+		scan := &repo.Scans[i]
+		var kept []findings.Finding
+		for j, f := range scan.Findings {
+			if duplicate[position{i, j}] {
+				scan.Duplicates = append(scan.Duplicates, f)
+				continue
+			}
+			kept = append(kept, f)
+		}
+		scan.Findings = kept
+	}
+}
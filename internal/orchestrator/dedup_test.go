@@ -0,0 +1,37 @@
+package orchestrator
+
+import (
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+func TestDedupRepo(t *testing.T) {
+	secret := func(scanner, rule string, sev findings.Severity) findings.Finding {
+		f := findings.Finding{Repo: "app", Scanner: scanner, RuleID: rule, Severity: sev, Path: "config.py", Line: 7, Refs: []string{"CWE-798"}}
+		f.Fingerprint = findings.Fingerprint(f, "")
+		return f
+	}
+	other := findings.Finding{Repo: "app", Scanner: "semgrep", RuleID: "sql-injection", Severity: findings.SeverityHigh, Path: "db.py", Line: 3}
+	repo := &report.Repo{Name: "app", Status: report.StatusScanned, Scans: []report.Scan{
+		{Scanner: "semgrep", Findings: []findings.Finding{secret("semgrep", "hardcoded-secret", findings.SeverityHigh), other}},
+		{Scanner: "cycode", Findings: []findings.Finding{secret("cycode", "generic-password", findings.SeverityCritical)}},
+	}}
+	dedupRepo(repo)
+
+	semgrep, cycode := repo.Scans[0], repo.Scans[1]
+	if len(semgrep.Findings) != 1 || semgrep.Findings[0].RuleID != "sql-injection" || len(semgrep.Duplicates) != 1 {
+		t.Fatalf("unexpected semgrep scan %+v", semgrep)
+	}
+	if len(cycode.Findings) != 1 || len(cycode.Findings[0].Scanners) != 2 {
+		t.Fatalf("canonical finding not merged: %+v", cycode.Findings)
+	}
+	if len(repo.Findings()) != 2 {
+		t.Fatalf("expected 2 findings after dedup, got %d", len(repo.Findings()))
+	}
+	if len(repo.Dedup) != 1 || repo.Dedup[0].Canonical != cycode.Findings[0].Fingerprint ||
+		repo.Dedup[0].Duplicates[0].Scanner != "semgrep" || repo.Dedup[0].Duplicates[0].Reason != "same CWE-798 at config.py:7" {
+		t.Fatalf("unexpected dedup decision %+v", repo.Dedup)
+	}
+}
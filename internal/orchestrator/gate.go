@@ -0,0 +1,45 @@
+package orchestrator
+
+import (
+	"fmt"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// GateError is returned by a run that has findings at or above the FailOn
+// severity or scanners that failed.
+type GateError struct {
+	Severity     findings.Severity
+	Findings     int
+	FailedScans  int
+	FailedClones int
+}
+
+func (e *GateError) Error() string {
+	return fmt.Sprintf("run failed the %s gate: %d findings at or above %s, %d failed scans, %d failed clones",
+		e.Severity, e.Findings, e.Severity, e.FailedScans, e.FailedClones)
+}
+
+// gate checks the report against failOn. A failed scan or clone fails the
+// gate too, since the repository cannot be shown to be clean.
+func gate(rep *report.Report, failOn findings.Severity) error {
+	if failOn == findings.SeverityUnknown {
+		return nil
+	}
+	e := &GateError{Severity: failOn, Findings: len(findings.Filter(rep.Findings(), failOn))}
+	for _, repo := range rep.Repos {
+		if repo.Status == report.StatusCloneFailed {
+			e.FailedClones++
+		}
+		for _, scan := range repo.Scans {
+			if scan.Error != "" {
+				e.FailedScans++
+			}
+		}
+	}
+	if e.Findings == 0 && e.FailedScans == 0 && e.FailedClones == 0 {
+		return nil
+	}
+	return e
+}
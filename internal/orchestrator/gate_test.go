@@ -0,0 +1,31 @@
+package orchestrator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+func TestGate(t *testing.T) {
+	rep := &report.Report{Repos: []*report.Repo{
+		{Name: "app", Status: report.StatusScanned, Scans: []report.Scan{
+			{Scanner: "trivy", Findings: []findings.Finding{{Severity: findings.SeverityMedium}}},
+		}},
+	}}
+	if err := gate(rep, findings.SeverityUnknown); err != nil {
+		t.Fatalf("disabled gate failed: %v", err)
+	}
+	if err := gate(rep, findings.SeverityHigh); err != nil {
+		t.Fatalf("medium finding failed the high gate: %v", err)
+	}
+	var ge *GateError
+	if err := gate(rep, findings.SeverityMedium); !errors.As(err, &ge) || ge.Findings != 1 {
+		t.Fatalf("expected medium gate failure, got %v", err)
+	}
+	rep.Repos = append(rep.Repos, &report.Repo{Name: "lib", Status: report.StatusCloneFailed, Reason: "network"})
+	if err := gate(rep, findings.SeverityCritical); !errors.As(err, &ge) || ge.FailedClones != 1 {
+		t.Fatalf("expected failed clone to fail the gate, got %v", err)
+	}
+}
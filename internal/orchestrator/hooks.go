@@ -0,0 +1,67 @@
+package orchestrator
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+
+	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/scanner"
+)
+
+// runHook runs one lifecycle hook of sc in dir and logs its output. repo is
+// empty for the run-wide setup and teardown hooks. The returned error is
+// redacted.
+func (r *Runner) runHook(ctx context.Context, sc config.Scanner, stage string, h config.Hook, dir, repo string) error {
+	if len(h.Command) == 0 {
+		return nil
+	}
+	attrs := []any{slog.String("scanner", sc.Name), slog.String("hook", stage)}
+	if repo != "" {
+		attrs = append(attrs, slog.String("repo", repo))
+	}
+	r.logger.Info("running hook", attrs...)
+	out, err := scanner.Scanner(sc).RunHook(ctx, h, dir)
+	output := r.cfg.Redactor.Redact(string(out))
+	if err != nil {
+		msg := r.cfg.Redactor.Redact(err.Error())
+		r.logger.Error("hook failed", append(attrs, slog.String("on_failure", h.OnFailure), slog.String("error", msg))...)
+		return fmt.Errorf("%s hook for %s failed: %s", stage, sc.Name, msg)
+	}
+	if len(output) > 0 {
+		r.logger.Info("hook output", append(attrs, slog.String("output", output))...)
+	}
+	return nil
+}
+
+// setupScanners runs the setup hook of every scanner in workDir and returns
+// the scanners that take part in the run.
+func (r *Runner) setupScanners(ctx context.Context, workDir string) ([]config.Scanner, error) {
+	var active []config.Scanner
+	for _, sc := range r.cfg.Scanners {
+		if err := r.runHook(ctx, sc, "setup", sc.Hooks.Setup, workDir, ""); err != nil {
+			switch sc.Hooks.Setup.OnFailure {
+			case config.OnFailureAbort:
+				return nil, err
+			case config.OnFailureSkipScanner:
+				r.logger.Warn("skipping scanner for this run", slog.String("scanner", sc.Name))
+				continue
+			}
+		}
+		active = append(active, sc)
+	}
+	return active, nil
+}
+
+// teardownScanners runs the teardown hook of every scanner in workDir. All
+// hooks run; the first failure with the abort policy is returned.
+func (r *Runner) teardownScanners(ctx context.Context, scanners []config.Scanner, workDir string) error {
+	var first error
+	for _, sc := range scanners {
+		err := r.runHook(ctx, sc, "teardown", sc.Hooks.Teardown, workDir, "")
+		if err != nil && first == nil && sc.Hooks.Teardown.OnFailure == config.OnFailureAbort {
+			first = err
+		}
+	}
+	return first
+}
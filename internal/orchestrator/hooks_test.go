@@ -0,0 +1,51 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/config"
+)
+
+func hookScanner(name string, setup config.Hook) config.Scanner {
+	return config.Scanner{Name: name, Command: []string{"true"}, Hooks: config.Hooks{Setup: setup}}
+}
+
+func TestSetupScanners(t *testing.T) {
+	fail := []string{"sh", "-c", "exit 1"}
+	r := &Runner{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Scanners: []config.Scanner{
+			hookScanner("ok", config.Hook{Command: []string{"true"}, OnFailure: config.OnFailureAbort}),
+			hookScanner("skipped", config.Hook{Command: fail, OnFailure: config.OnFailureSkipScanner}),
+			hookScanner("ignored", config.Hook{Command: fail, OnFailure: config.OnFailureIgnore}),
+		}},
+	}
+	active, err := r.setupScanners(context.Background(), t.TempDir())
+	if err != nil {
+		t.Fatalf("setup failed: %v", err)
+	}
+	if len(active) != 2 || active[0].Name != "ok" || active[1].Name != "ignored" {
+		t.Fatalf("unexpected active scanners: %+v", active)
+	}
+
+	r.cfg.Scanners = append(r.cfg.Scanners, hookScanner("fatal", config.Hook{Command: fail, OnFailure: config.OnFailureAbort}))
+	if _, err := r.setupScanners(context.Background(), t.TempDir()); err == nil {
+		t.Fatalf("expected abort policy to fail the run")
+	}
+}
+
+func TestTeardownScanners(t *testing.T) {
+	r := &Runner{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), cfg: &config.Config{}}
+	dir := t.TempDir()
+	ignored := config.Scanner{Name: "a", Hooks: config.Hooks{Teardown: config.Hook{Command: []string{"false"}, OnFailure: config.OnFailureIgnore}}}
+	if err := r.teardownScanners(context.Background(), []config.Scanner{ignored}, dir); err != nil {
+		t.Fatalf("ignored teardown failure returned %v", err)
+	}
+	fatal := config.Scanner{Name: "b", Hooks: config.Hooks{Teardown: config.Hook{Command: []string{"false"}, OnFailure: config.OnFailureAbort}}}
+	if err := r.teardownScanners(context.Background(), []config.Scanner{fatal, ignored}, dir); err == nil {
+		t.Fatalf("expected abort teardown failure to be returned")
+	}
+}
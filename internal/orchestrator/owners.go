@@ -0,0 +1,65 @@
+package orchestrator
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"sync"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/owners"
+)
+
+// ownerResolver attributes the findings of one repository to owners.
+type ownerResolver struct {
+	codeowners *owners.Codeowners
+	fallback   func() string
+}
+
+// newOwnerResolver reads the CODEOWNERS file of the clone at dir. The owner
+// of paths it does not cover comes from repository metadata or the owners
+// configuration and is looked up once, when first needed.
+func (r *Runner) newOwnerResolver(ctx context.Context, org, repo, dir string, topics []string) *ownerResolver {
+	co, err := owners.Load(dir)
+	if err != nil {
+		r.logger.Warn("ignoring invalid CODEOWNERS", slog.String("repo", repo), slog.Any("error", err))
+	}
+	return &ownerResolver{
+		codeowners: co,
+		fallback:   sync.OnceValue(func() string { return r.fallbackOwner(ctx, org, repo, topics) }),
+	}
+}
+
+func (r *Runner) fallbackOwner(ctx context.Context, org, repo string, topics []string) string {
+	policy := r.cfg.Owners
+	if policy.Property != "" && r.client != nil {
+		props, err := r.client.RepoProperties(ctx, repo)
+		if err != nil {
+			r.logger.Warn("unable to read repository properties", slog.String("repo", repo), slog.Any("error", err))
+		} else if owner := props[policy.Property]; owner != "" {
+			return owner
+		}
+	}
+	if policy.TopicPrefix != "" {
+		for _, topic := range topics {
+			if team, ok := strings.CutPrefix(topic, policy.TopicPrefix); ok && team != "" {
+				return "@" + org + "/" + team
+			}
+		}
+	}
+	return policy.RepoOwner(repo)
+}
+
+// owner returns the owners of path, separated by spaces.
+func (o *ownerResolver) owner(path string) string {
+	if list := o.codeowners.Owners(path); len(list) > 0 {
+		return strings.Join(list, " ")
+	}
+	return o.fallback()
+}
+
+func (o *ownerResolver) assign(list []findings.Finding) {
+	for i := range list {
+		list[i].Owner = o.owner(list[i].Path)
+	}
+}
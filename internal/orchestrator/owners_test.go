@@ -0,0 +1,43 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+func TestOwnerResolver(t *testing.T) {
+	r := &Runner{
+		logger: slog.New(slog.NewTextHandler(io.Discard, nil)),
+		cfg: &config.Config{Owners: config.OwnerPolicy{
+			TopicPrefix: "team-",
+			Repos:       []config.OwnerRule{{Match: "legacy-*", Owner: "@acme/legacy"}},
+		}},
+	}
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, ".github"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".github", "CODEOWNERS"), []byte("/infra/ @acme/sre @alice\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	list := []findings.Finding{{Path: "infra/main.tf"}, {Path: "app/main.go"}}
+	r.newOwnerResolver(ctx, "acme", "app", dir, []string{"go", "team-payments"}).assign(list)
+	if list[0].Owner != "@acme/sre @alice" || list[1].Owner != "@acme/payments" {
+		t.Fatalf("unexpected owners %q, %q", list[0].Owner, list[1].Owner)
+	}
+
+	// without CODEOWNERS or a team topic the owner map applies
+	list = []findings.Finding{{Path: "main.go"}}
+	r.newOwnerResolver(ctx, "acme", "legacy-api", t.TempDir(), nil).assign(list)
+	if list[0].Owner != "@acme/legacy" {
+		t.Fatalf("unexpected owner %q", list[0].Owner)
+	}
+}
@@ -0,0 +1,142 @@
+package orchestrator
+
+import (
+	github "github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/config"
+	internalgithub "github.com/cybrota/eskimo/internal/github"
+	"github.com/cybrota/eskimo/internal/owners"
+	"github.com/cybrota/eskimo/internal/report"
+	"github.com/cybrota/eskimo/internal/suppress"
+)
+
+// repoState returns the special state of a repository from its API metadata,
+// or "" for an active repository with content.
+func repoState(rp *github.Repository) string {
+	switch {
+	case rp.GetDisabled():
+		return "disabled"
+	case rp.GetArchived():
+		return "archived"
+	case isEmpty(rp):
+		return "empty"
+	}
+	return ""
+}
+
+// isEmpty reports whether a repository has never received a push. Size alone
+// lags behind fresh pushes, so the push timestamp is checked as well.
+func isEmpty(rp *github.Repository) bool {
+	if rp.GetSize() != 0 {
+		return false
+	}
+	if rp.PushedAt == nil || rp.CreatedAt == nil {
+		return rp.PushedAt == nil
+	}
+	return !rp.PushedAt.After(rp.CreatedAt.Time)
+}
+
+// skipReason returns the state that excludes rp from scanning under policy, or "".
+func skipReason(policy config.RepoPolicy, rp *github.Repository) string {
+	state := repoState(rp)
+	var p string
+	switch state {
+	case "disabled":
+		p = policy.Disabled
+	case "archived":
+		p = policy.Archived
+	case "empty":
+		p = policy.Empty
+	default:
+		return ""
+	}
+	if p == config.PolicyScan {
+		return ""
+	}
+	return state
+}
+
+// cloneOptions applies the repository rules matching rp to the run defaults.
+// It returns a non-empty skip reason when the repository exceeds a size limit
+// whose oversize action is skip.
+func cloneOptions(defaults internalgithub.CloneOptions, rules []config.RepoRule, rp *github.Repository) (internalgithub.CloneOptions, string) {
+	opts := defaults
+	maxSizeMB, oversize := 0, ""
+	for _, rule := range rules {
+		if !rule.Matches(rp.GetName()) {
+			continue
+		}
+		if rule.Submodules != nil {
+			opts.Submodules = *rule.Submodules
+		}
+		if rule.LFS != "" {
+			opts.LFS = internalgithub.LFSMode(rule.LFS)
+		}
+		if rule.Strategy != "" {
+			opts.Strategy = internalgithub.CloneStrategy(rule.Strategy)
+		}
+		if len(rule.SparsePaths) > 0 {
+			opts.SparsePaths = rule.SparsePaths
+		}
+		if rule.MaxSizeMB > 0 {
+			maxSizeMB, oversize = rule.MaxSizeMB, rule.Oversize
+		}
+	}
+	// the API reports repository size in kilobytes
+	if maxSizeMB > 0 && rp.GetSize() > maxSizeMB*1024 {
+		if oversize != "sparse" {
+			return opts, "oversize"
+		}
+		opts.Strategy = internalgithub.CloneSparse
+	}
+	if opts.Strategy == internalgithub.CloneSparse {
+		opts.SparsePaths = append(append([]string{}, opts.SparsePaths...), sparseMetadata()...)
+	}
+	return opts, ""
+}
+
+// sparseMetadata returns the patterns of the repository files the runner
+// reads itself, which sparse clones always check out.
+func sparseMetadata() []string {
+	paths := []string{"/" + suppress.FileName}
+	for _, loc := range owners.Locations {
+		paths = append(paths, "/"+loc)
+	}
+	return paths
+}
+
+// historyOptions enables history fetching when any scanner needs it. Scanners
+// asking for different bounds get full history.
+func historyOptions(opts internalgithub.CloneOptions, scanners []config.Scanner) internalgithub.CloneOptions {
+	seen := false
+	for _, sc := range scanners {
+		if !sc.NeedsHistory {
+			continue
+		}
+		if !seen {
+			opts.History, opts.HistorySince, seen = true, sc.HistorySince, true
+		} else if opts.HistorySince != sc.HistorySince {
+			opts.HistorySince = ""
+		}
+	}
+	return opts
+}
+
+func cloneInfo(opts internalgithub.CloneOptions) *report.CloneInfo {
+	lfs := string(opts.LFS)
+	if lfs == "" {
+		lfs = "default"
+	}
+	strategy := string(opts.Strategy)
+	if strategy == "" {
+		strategy = string(internalgithub.CloneShallow)
+	}
+	history := "shallow"
+	if opts.History {
+		history = "full"
+		if opts.HistorySince != "" {
+			history = "since " + opts.HistorySince
+		}
+	}
+	return &report.CloneInfo{Strategy: strategy, History: history, Submodules: opts.Submodules, LFS: lfs}
+}
@@ -0,0 +1,100 @@
+package orchestrator
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	github "github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/config"
+	internalgithub "github.com/cybrota/eskimo/internal/github"
+)
+
+func TestSkipReason(t *testing.T) {
+	created := &github.Timestamp{Time: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	pushed := &github.Timestamp{Time: created.Add(time.Hour)}
+	skipAll := config.RepoPolicy{Archived: config.PolicySkip, Disabled: config.PolicySkip, Empty: config.PolicySkip}
+	cases := []struct {
+		name   string
+		repo   *github.Repository
+		policy config.RepoPolicy
+		want   string
+	}{
+		{"active", &github.Repository{Size: github.Int(10), CreatedAt: created, PushedAt: pushed}, skipAll, ""},
+		{"archived", &github.Repository{Archived: github.Bool(true), Size: github.Int(10)}, skipAll, "archived"},
+		{"archived scanned", &github.Repository{Archived: github.Bool(true), Size: github.Int(10)}, config.RepoPolicy{Archived: config.PolicyScan}, ""},
+		{"disabled", &github.Repository{Disabled: github.Bool(true)}, skipAll, "disabled"},
+		{"empty", &github.Repository{Size: github.Int(0), CreatedAt: created, PushedAt: created}, skipAll, "empty"},
+		{"fresh push", &github.Repository{Size: github.Int(0), CreatedAt: created, PushedAt: pushed}, skipAll, ""},
+	}
+	for _, tc := range cases {
+		if got := skipReason(tc.policy, tc.repo); got != tc.want {
+			t.Errorf("%s: expected %q, got %q", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestCloneOptionsRules(t *testing.T) {
+	yes, no := true, false
+	rules := []config.RepoRule{
+		{Match: "platform-*", Submodules: &yes, LFS: "fetch"},
+		{Match: "platform-legacy", Submodules: &no},
+	}
+	defaults := internalgithub.CloneOptions{LFS: internalgithub.LFSSkip}
+
+	opts, _ := cloneOptions(defaults, rules, &github.Repository{Name: github.String("platform-api")})
+	if !opts.Submodules || opts.LFS != internalgithub.LFSFetch {
+		t.Fatalf("unexpected options for platform-api: %+v", opts)
+	}
+	opts, _ = cloneOptions(defaults, rules, &github.Repository{Name: github.String("platform-legacy")})
+	if opts.Submodules || opts.LFS != internalgithub.LFSFetch {
+		t.Fatalf("later rule should override submodules: %+v", opts)
+	}
+	opts, _ = cloneOptions(defaults, rules, &github.Repository{Name: github.String("web")})
+	if opts.Submodules || opts.LFS != defaults.LFS {
+		t.Fatalf("unmatched repo should use defaults: %+v", opts)
+	}
+}
+
+func TestCloneOptionsOversize(t *testing.T) {
+	rules := []config.RepoRule{
+		{Match: "mono", MaxSizeMB: 100, Oversize: "sparse", SparsePaths: []string{"/infra/"}},
+		{Match: "assets", MaxSizeMB: 100},
+	}
+	opts, skip := cloneOptions(internalgithub.CloneOptions{}, rules, &github.Repository{Name: github.String("mono"), Size: github.Int(500 * 1024)})
+	if skip != "" || opts.Strategy != internalgithub.CloneSparse || opts.SparsePaths[0] != "/infra/" {
+		t.Fatalf("expected sparse clone for oversized mono, got %+v (skip %q)", opts, skip)
+	}
+	for _, path := range []string{"/.eskimo-ignore.yaml", "/CODEOWNERS", "/.github/CODEOWNERS"} {
+		if !slices.Contains(opts.SparsePaths, path) {
+			t.Fatalf("sparse clone must check out %s: %v", path, opts.SparsePaths)
+		}
+	}
+	if len(rules[0].SparsePaths) != 1 {
+		t.Fatalf("rule sparse paths must not be modified: %v", rules[0].SparsePaths)
+	}
+	_, skip = cloneOptions(internalgithub.CloneOptions{}, rules, &github.Repository{Name: github.String("assets"), Size: github.Int(500 * 1024)})
+	if skip != "oversize" {
+		t.Fatalf("expected oversized assets to be skipped, got %q", skip)
+	}
+	_, skip = cloneOptions(internalgithub.CloneOptions{}, rules, &github.Repository{Name: github.String("assets"), Size: github.Int(10)})
+	if skip != "" {
+		t.Fatalf("small repo must not be skipped")
+	}
+}
+
+func TestHistoryOptions(t *testing.T) {
+	shallow := []config.Scanner{{Name: "semgrep"}}
+	if opts := historyOptions(internalgithub.CloneOptions{}, shallow); opts.History {
+		t.Fatalf("history not needed: %+v", opts)
+	}
+	bounded := append(shallow, config.Scanner{Name: "gitleaks", NeedsHistory: true, HistorySince: "90 days ago"})
+	if opts := historyOptions(internalgithub.CloneOptions{}, bounded); !opts.History || opts.HistorySince != "90 days ago" {
+		t.Fatalf("expected bounded history: %+v", opts)
+	}
+	mixed := append(bounded, config.Scanner{Name: "trufflehog", NeedsHistory: true})
+	if opts := historyOptions(internalgithub.CloneOptions{}, mixed); !opts.History || opts.HistorySince != "" {
+		t.Fatalf("expected full history: %+v", opts)
+	}
+}
@@ -11,31 +11,60 @@ import (
 	"fmt"
 	"log/slog"
 	"os"
+	"path"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	github "github.com/google/go-github/v55/github"
 
 	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/findings"
 	internalgithub "github.com/cybrota/eskimo/internal/github"
+	"github.com/cybrota/eskimo/internal/report"
 	"github.com/cybrota/eskimo/internal/scanner"
+	"github.com/cybrota/eskimo/internal/suppress"
 )
 
 const SampleLimit = 10
 
 type scanLog struct {
-	repo    string
-	scanner string
-	output  string
-	err     error
+	repo       string
+	sha        string
+	scanner    string
+	format     string
+	output     string
+	err        error
+	artifacts  []string
+	findings   []findings.Finding
+	suppressed []report.Suppressed
 }
 
 type Options struct {
 	ClonePath string
 	Sample    bool
+	Clone     internalgithub.CloneOptions
+	// ArtifactsPath is where each run keeps a directory of scanner result
+	// files. Empty discards them after the run.
+	ArtifactsPath string
+	// PublishArtifacts records the scanner result files in the report even
+	// without ArtifactsPath, so sinks such as S3 upload them before they are
+	// discarded.
+	PublishArtifacts bool
+	// Sinks receive the run report after all repositories are scanned.
+	Sinks []report.Sink
+	// FailOn makes a run return a *GateError when it has findings at or above
+	// this severity or failed scans. SeverityUnknown disables the gate.
+	FailOn findings.Severity
+	// Suppressions hide matching findings of every repository. Each clone
+	// may add its own in a suppress.FileName file.
+	Suppressions suppress.List
+	// Dedup merges findings of different scanners that describe the same
+	// issue into one canonical finding.
+	Dedup bool
 }
 
 type Runner struct {
@@ -54,43 +83,22 @@ func NewRunner(logger *slog.Logger, client *internalgithub.Client, cfg *config.C
 	}
 }
 
-func (r *Runner) runPreCommands(ctx context.Context, workDir string) error {
-	for _, sc := range r.cfg.Scanners {
-		if len(sc.PreCommand) == 0 {
-			continue
-		}
-		r.logger.Info("running pre-command", slog.String("scanner", sc.Name))
-		s := scanner.Scanner(sc)
-		out, err := s.RunPreCommand(ctx, workDir)
-		if err != nil {
-			r.logger.Error("pre-command failed", slog.String("scanner", sc.Name), slog.Any("error", err), slog.String("output", string(out)))
-			return fmt.Errorf("pre-command for %s failed: %w", sc.Name, err)
-		}
-		if len(out) > 0 {
-			r.logger.Info("pre-command output", slog.String("scanner", sc.Name), slog.String("output", string(out)))
-		}
-	}
-	return nil
+// Target is a repository to scan. When SHA is set the clone is checked out at
+// that commit instead of the default branch head. Ref names where SHA came
+// from; a ref other than the default branch, such as a pull request head, is
+// recorded in the report so sinks tracking the default branch ignore it.
+type Target struct {
+	Repo *github.Repository
+	SHA  string
+	Ref  string
 }
 
+// Run scans every repository of the organization.
 func (r *Runner) Run(ctx context.Context) error {
 	repos, err := r.client.ListRepos(ctx)
 	if err != nil {
 		return err
 	}
-	baseDir, err := sanitizeClonePath(r.opts.ClonePath)
-	if err != nil {
-		return err
-	}
-	baseDir, err = ensureCloneBase(baseDir)
-	if err != nil {
-		return err
-	}
-	r.logger.Info("using clone path", slog.String("path", baseDir))
-
-	if err := r.runPreCommands(ctx, baseDir); err != nil {
-		return err
-	}
 
 	totalRepos := len(repos)
 	r.logger.Info("repositories discovered", slog.Int("count", totalRepos))
@@ -107,52 +115,174 @@ func (r *Runner) Run(ctx context.Context) error {
 		r.logger.Info("sampling repositories", slog.Int("count", len(repos)), slog.Int("limit", SampleLimit))
 	}
 
+	targets := make([]Target, 0, len(repos))
+	for _, repo := range repos {
+		targets = append(targets, Target{Repo: repo})
+	}
+	return r.RunTargets(ctx, targets)
+}
+
+// RunTargets clones and scans the given repositories.
+func (r *Runner) RunTargets(ctx context.Context, targets []Target) error {
+	rep := &report.Report{Org: r.client.Org(), StartedAt: time.Now().UTC()}
+	baseDir, err := sanitizeClonePath(r.opts.ClonePath)
+	if err != nil {
+		return err
+	}
+	baseDir, err = ensureCloneBase(baseDir)
+	if err != nil {
+		return err
+	}
+	r.logger.Info("using clone path", slog.String("path", baseDir))
+	rep.RunID = rep.StartedAt.Format(runIDLayout)
+	runDir, keepArtifacts, err := r.runDirectory(rep.RunID)
+	if err != nil {
+		return err
+	}
+	if !keepArtifacts {
+		defer os.RemoveAll(runDir)
+	}
+	recordArtifacts := keepArtifacts || r.opts.PublishArtifacts
+	defaults := historyOptions(r.opts.Clone, r.cfg.Scanners)
+	r.logExpired(r.opts.Suppressions, rep.StartedAt)
+
+	scanners, err := r.setupScanners(ctx, baseDir)
+	if err != nil {
+		return err
+	}
+	// a hook failing with the abort policy cancels the remaining work
+	runCtx, abort := context.WithCancelCause(ctx)
+	defer abort(nil)
+
 	parallel := runtime.NumCPU() * 4
 	sem := make(chan struct{}, parallel)
 	type repoInfo struct {
-		name string
-		path string
+		name          string
+		path          string
+		sha           string
+		defaultBranch string
+		language      string
+		topics        []string
+	}
+	repoCh := make(chan repoInfo, len(targets))
+	clonedRepos := make([]repoInfo, 0, len(targets))
+	var mu sync.Mutex
+	var unscanned []*report.Repo
+	clones := make(map[string]*report.CloneInfo)
+	refs := make(map[string]string)
+	for _, t := range targets {
+		if t.Ref != "" && t.Ref != "refs/heads/"+t.Repo.GetDefaultBranch() {
+			refs[t.Repo.GetName()] = t.Ref
+		}
+	}
+	record := func(name, status, reason string) {
+		mu.Lock()
+		unscanned = append(unscanned, &report.Repo{Name: name, Status: status, Reason: reason})
+		mu.Unlock()
+	}
+	recordFailure := func(name string, err error) {
+		kind := internalgithub.CloneUnknown
+		var ce *internalgithub.CloneError
+		if errors.As(err, &ce) {
+			kind = ce.Kind
+		}
+		// empty and locked repositories are expected states, not failures
+		if kind == internalgithub.CloneEmpty || kind == internalgithub.CloneLocked {
+			r.logger.Info("skipping repository", slog.String("repo", name), slog.String("reason", string(kind)))
+			record(name, report.StatusSkipped, string(kind))
+			return
+		}
+		r.logger.Error("failed to clone repository", slog.String("repo", name), slog.Any("error", err))
+		record(name, report.StatusCloneFailed, string(kind))
 	}
-	repoCh := make(chan repoInfo, len(repos))
-	clonedRepos := make([]repoInfo, 0, len(repos))
 	var cloneWG sync.WaitGroup
-	for _, repo := range repos {
+	for _, target := range targets {
 		cloneWG.Add(1)
 		sem <- struct{}{}
-		go func(rp *github.Repository) {
+		go func(t Target) {
 			defer cloneWG.Done()
+			rp := t.Repo
 			repoName := rp.GetName()
+			if reason := skipReason(r.cfg.Repositories, rp); reason != "" {
+				r.logger.Info("skipping repository", slog.String("repo", repoName), slog.String("reason", reason))
+				record(repoName, report.StatusSkipped, reason)
+				<-sem
+				return
+			}
 			r.logger.Info("preparing repository", slog.String("repo", repoName))
 			repoPath := filepath.Join(baseDir, repoName)
 			removed, err := removeExistingRepo(repoPath)
 			if err != nil {
 				r.logger.Error("failed to prepare repository directory", slog.String("repo", repoName), slog.String("path", repoPath), slog.Any("error", err))
+				recordFailure(repoName, err)
 				<-sem
 				return
 			}
 			if removed {
 				r.logger.Info("removed existing repository directory", slog.String("repo", repoName), slog.String("path", repoPath))
 			}
-			r.logger.Info("cloning repository", slog.String("repo", repoName), slog.String("path", repoPath))
-			repoPath, err = r.client.CloneRepo(rp, baseDir)
+			opts, reason := cloneOptions(defaults, r.cfg.Repositories.Rules, rp)
+			if reason != "" {
+				r.logger.Info("skipping repository", slog.String("repo", repoName), slog.String("reason", reason), slog.Int("size_kb", rp.GetSize()))
+				record(repoName, report.StatusSkipped, reason)
+				<-sem
+				return
+			}
+			info := cloneInfo(opts)
+			r.logger.Info("cloning repository", slog.String("repo", repoName), slog.String("path", repoPath), slog.String("strategy", info.Strategy), slog.Bool("submodules", info.Submodules), slog.String("lfs", info.LFS))
+			repoPath, err = r.client.CloneRepo(runCtx, rp, baseDir, opts)
 			if err != nil {
-				r.logger.Error("failed to clone repository", slog.String("repo", repoName), slog.Any("error", err))
+				recordFailure(repoName, err)
 				<-sem
 				return
 			}
-			repoCh <- repoInfo{name: repoName, path: repoPath}
+			if t.SHA != "" {
+				if err := r.client.Checkout(runCtx, repoPath, t.SHA, opts); err != nil {
+					r.logger.Error("failed to check out commit", slog.String("repo", repoName), slog.String("sha", t.SHA), slog.Any("error", err))
+					recordFailure(repoName, err)
+					<-sem
+					return
+				}
+			}
+			sha, err := r.client.HeadSHA(repoPath)
+			if err != nil {
+				r.logger.Warn("unable to resolve checked out commit", slog.String("repo", repoName), slog.Any("error", err))
+			}
+			mu.Lock()
+			clones[repoName] = info
+			mu.Unlock()
+			repoCh <- repoInfo{name: repoName, path: repoPath, sha: sha, defaultBranch: rp.GetDefaultBranch(), language: rp.GetLanguage(), topics: rp.Topics}
 			<-sem
-		}(repo)
+		}(target)
 	}
 	cloneWG.Wait()
 	close(repoCh)
+	rep.Repos = append(rep.Repos, unscanned...)
 
-	logCh := make(chan scanLog, len(repos)*len(r.cfg.Scanners))
+	logCh := make(chan scanLog, len(targets)*len(scanners))
 	var logWG sync.WaitGroup
 	logWG.Add(1)
 	go func() {
 		defer logWG.Done()
 		for l := range logCh {
+			l.output = r.cfg.Redactor.Redact(l.output)
+			if l.err != nil {
+				l.err = errors.New(r.cfg.Redactor.Redact(l.err.Error()))
+			}
+			for i := range l.findings {
+				l.findings[i].Message = r.cfg.Redactor.Redact(l.findings[i].Message)
+			}
+			for i := range l.suppressed {
+				l.suppressed[i].Finding.Message = r.cfg.Redactor.Redact(l.suppressed[i].Finding.Message)
+			}
+			sr := report.Scan{Scanner: l.scanner, Format: l.format, Output: l.output, Artifacts: l.artifacts, Findings: l.findings, Suppressed: l.suppressed}
+			if l.err != nil {
+				sr.Error = l.err.Error()
+			}
+			rr := rep.Repo(l.repo)
+			rr.SHA = l.sha
+			rr.Status = report.StatusScanned
+			rr.Scans = append(rr.Scans, sr)
 			prefix := fmt.Sprintf("%s: %s", l.repo, l.scanner)
 			if l.err != nil {
 				if l.output != "" {
@@ -180,26 +310,108 @@ func (r *Runner) Run(ctx context.Context) error {
 		scanSem <- struct{}{}
 		go func(in repoInfo) {
 			defer scanWG.Done()
+			sup := r.repoSuppressions(in.name, in.path, rep.StartedAt)
+			own := r.newOwnerResolver(runCtx, rep.Org, in.name, in.path, in.topics)
+			// hooks may rewrite the checkout, e.g. npm ci, so every
+			// before_repo hook runs before any scanner starts and every
+			// after_repo hook once all of them have finished
+			var active []config.Scanner
+			for _, sc := range scanners {
+				err := r.runHook(runCtx, sc, "before_repo", sc.Hooks.BeforeRepo, in.path, in.name)
+				if err != nil {
+					switch sc.Hooks.BeforeRepo.OnFailure {
+					case config.OnFailureAbort:
+						abort(err)
+						fallthrough
+					case config.OnFailureSkipScanner:
+						logCh <- scanLog{repo: in.name, sha: in.sha, scanner: sc.Name, err: err}
+						continue
+					}
+				}
+				active = append(active, sc)
+			}
+			logs := make([]scanLog, len(active))
 			var wg sync.WaitGroup
-			for _, sc := range r.cfg.Scanners {
+			for i, sc := range active {
 				scCopy := sc
-				scCopy.PreCommand = nil
 				wg.Add(1)
 				go func() {
 					defer wg.Done()
 					s := scanner.Scanner(scCopy)
 					r.logger.Info("running scanner", slog.String("repo", in.name), slog.String("scanner", scCopy.Name))
-					out, err := s.Run(ctx, in.path)
-					logCh <- scanLog{repo: in.name, scanner: scCopy.Name, output: string(out), err: err}
+					outDir := filepath.Join(runDir, in.name, scCopy.Name)
+					res, err := s.Scan(runCtx, config.TemplateVars{
+						Repo:          in.name,
+						Org:           rep.Org,
+						Path:          in.path,
+						SHA:           in.sha,
+						DefaultBranch: in.defaultBranch,
+						OutputDir:     outDir,
+						Language:      in.language,
+					})
+					l := scanLog{repo: in.name, sha: in.sha, scanner: scCopy.Name, format: scCopy.Format, output: string(res.Output), err: err}
+					if recordArtifacts {
+						for _, a := range res.Artifacts {
+							l.artifacts = append(l.artifacts, path.Join(in.name, scCopy.Name, a))
+						}
+					}
+					if scCopy.Format == config.FormatSARIF {
+						// scanners such as gitleaks exit non-zero when they
+						// report findings; the scan only failed if it left no
+						// SARIF results behind
+						list, parseErr := scanFindings(in.name, scCopy.Name, res, outDir)
+						switch {
+						case parseErr == nil:
+							if err != nil {
+								r.logger.Info("scanner exited with an error but produced results", slog.String("repo", in.name), slog.String("scanner", scCopy.Name), slog.Any("error", err))
+							}
+							l.err = nil
+							scCopy.Severity.Apply(list)
+							own.assign(list)
+							l.findings, l.suppressed = applySuppressions(list, sup, rep.StartedAt)
+						case err == nil:
+							l.err = parseErr
+						}
+					}
+					logs[i] = l
 				}()
 			}
 			wg.Wait()
+			for i, sc := range active {
+				err := r.runHook(runCtx, sc, "after_repo", sc.Hooks.AfterRepo, in.path, in.name)
+				if err != nil && sc.Hooks.AfterRepo.OnFailure == config.OnFailureAbort {
+					abort(err)
+				}
+				logCh <- logs[i]
+			}
 			<-scanSem
 		}(info)
 	}
 	scanWG.Wait()
 	close(logCh)
 	logWG.Wait()
+	if r.opts.Dedup {
+		for _, repo := range rep.Repos {
+			dedupRepo(repo)
+		}
+	}
+	for _, repo := range rep.Repos {
+		repo.Clone = clones[repo.Name]
+		repo.Ref = refs[repo.Name]
+	}
+	teardownErr := r.teardownScanners(ctx, scanners, baseDir)
+	if recordArtifacts {
+		removed, err := removeEmptyDirs(runDir)
+		if err != nil {
+			r.logger.Warn("unable to prune artifacts directory", slog.String("path", runDir), slog.Any("error", err))
+		}
+		if !removed {
+			rep.ArtifactsDir = runDir
+			if keepArtifacts {
+				r.logger.Info("scanner artifacts kept", slog.String("path", runDir))
+			}
+		}
+	}
 
 	for _, repo := range clonedRepos {
 		if err := os.RemoveAll(repo.path); err != nil {
@@ -215,9 +427,50 @@ func (r *Runner) Run(ctx context.Context) error {
 		}
 	}
 
-	r.logger.Info("scanning completed successfully", slog.Int("repositories", len(clonedRepos)))
+	if err := context.Cause(runCtx); err != nil && ctx.Err() == nil {
+		r.logger.Error("run aborted by hook", slog.Any("error", err))
+		return err
+	}
+	if teardownErr != nil {
+		return teardownErr
+	}
+
+	rep.FinishedAt = time.Now().UTC()
+	r.publish(ctx, rep)
+
+	r.logSummary(rep)
 
-	return nil
+	return gate(rep, r.opts.FailOn)
+}
+
+// logSummary logs the run totals and every repository that was not scanned.
+func (r *Runner) logSummary(rep *report.Report) {
+	counts := make(map[string]int)
+	for _, repo := range rep.Repos {
+		counts[repo.Status]++
+		switch repo.Status {
+		case report.StatusCloneFailed:
+			r.logger.Warn("repository not scanned", slog.String("repo", repo.Name), slog.String("status", repo.Status), slog.String("reason", repo.Reason))
+		case report.StatusSkipped:
+			r.logger.Info("repository not scanned", slog.String("repo", repo.Name), slog.String("status", repo.Status), slog.String("reason", repo.Reason))
+		}
+	}
+	r.logger.Info("scanning completed",
+		slog.Int("repositories", len(rep.Repos)),
+		slog.Int("scanned", counts[report.StatusScanned]),
+		slog.Int("skipped", counts[report.StatusSkipped]),
+		slog.Int("clone_failed", counts[report.StatusCloneFailed]),
+		slog.Int("findings", len(rep.Findings())),
+		slog.Int("suppressed", len(rep.Suppressed())))
+}
+
+func (r *Runner) publish(ctx context.Context, rep *report.Report) {
+	for _, sink := range r.opts.Sinks {
+		r.logger.Info("publishing report", slog.String("sink", sink.Name()))
+		if err := sink.Publish(ctx, rep); err != nil {
+			r.logger.Error("failed to publish report", slog.String("sink", sink.Name()), slog.Any("error", err))
+		}
+	}
 }
 
 func sanitizeClonePath(raw string) (string, error) {
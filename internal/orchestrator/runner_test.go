@@ -0,0 +1,179 @@
+package orchestrator
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/google/go-github/v55/github"
+
+	"github.com/cybrota/eskimo/internal/config"
+	internalgithub "github.com/cybrota/eskimo/internal/github"
+	"github.com/cybrota/eskimo/internal/report"
+	"github.com/cybrota/eskimo/internal/secrets"
+)
+
+type captureSink struct {
+	rep *report.Report
+}
+
+func (s *captureSink) Name() string {
+	return "capture"
+}
+
+func (s *captureSink) Publish(ctx context.Context, rep *report.Report) error {
+	s.rep = rep
+	return nil
+}
+
+// gitRepo creates a repository with one commit holding files.
+func gitRepo(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, args := range [][]string{{"init"}, {"add", "."}, {"commit", "-m", "init"}} {
+		cmd := exec.Command("git", args...)
+		cmd.Dir = dir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	return dir
+}
+
+const leakSARIF = `{"runs": [{"tool": {"driver": {"name": "leaks"}}, "results": [
+  {"ruleId": "aws-key", "level": "error", "message": {"text": "AWS key"},
+   "locations": [{"physicalLocation": {"artifactLocation": {"uri": "main.go"}, "region": {"startLine": 1}}}]}]}]}`
+
+func TestRunTargetsParsesFindingsOnNonZeroExit(t *testing.T) {
+	remote := gitRepo(t, map[string]string{"main.go": "package main\n", "leaks.sarif": leakSARIF})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		Scanners: []config.Scanner{
+			// exits 1 because it found something, like gitleaks does
+			{Name: "leaks", Command: []string{"sh", "-c", "cat leaks.sarif; exit 1"}, Format: config.FormatSARIF},
+			{Name: "broken", Command: []string{"sh", "-c", "echo boom; exit 2"}, Format: config.FormatSARIF},
+		},
+		Redactor: secrets.NewRedactor(),
+	}
+	sink := &captureSink{}
+	r := NewRunner(logger, internalgithub.NewClient(logger, "", "acme"), cfg, Options{ClonePath: t.TempDir(), Sinks: []report.Sink{sink}})
+	target := Target{Repo: &github.Repository{Name: github.String("app"), CloneURL: github.String(remote), Size: github.Int(1)}}
+	if err := r.RunTargets(context.Background(), []Target{target}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if sink.rep == nil || len(sink.rep.Repos) != 1 {
+		t.Fatalf("unexpected report %+v", sink.rep)
+	}
+	scans := make(map[string]report.Scan)
+	for _, scan := range sink.rep.Repos[0].Scans {
+		scans[scan.Scanner] = scan
+	}
+	if leaks := scans["leaks"]; leaks.Error != "" || len(leaks.Findings) != 1 || leaks.Findings[0].RuleID != "aws-key" {
+		t.Errorf("findings of a scanner exiting 1 were dropped: %+v", leaks)
+	}
+	if broken := scans["broken"]; broken.Error == "" {
+		t.Errorf("scanner without SARIF output not marked failed: %+v", broken)
+	}
+}
+
+// artifactSink reads the artifacts of the report while it is published.
+type artifactSink struct {
+	data []string
+}
+
+func (s *artifactSink) Name() string {
+	return "artifacts"
+}
+
+func (s *artifactSink) Publish(ctx context.Context, rep *report.Report) error {
+	for _, repo := range rep.Repos {
+		for _, scan := range repo.Scans {
+			for _, a := range scan.Artifacts {
+				data, err := os.ReadFile(filepath.Join(rep.ArtifactsDir, filepath.FromSlash(a)))
+				if err != nil {
+					return err
+				}
+				s.data = append(s.data, string(data))
+			}
+		}
+	}
+	return nil
+}
+
+func TestRunTargetsPublishesArtifacts(t *testing.T) {
+	remote := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{
+		Scanners: []config.Scanner{{Name: "lint", Command: []string{"sh", "-c", "echo result > $ESKIMO_OUTPUT_DIR/out.txt"}}},
+		Redactor: secrets.NewRedactor(),
+	}
+	sink := &artifactSink{}
+	r := NewRunner(logger, internalgithub.NewClient(logger, "", "acme"), cfg, Options{ClonePath: t.TempDir(), PublishArtifacts: true, Sinks: []report.Sink{sink}})
+	target := Target{Repo: &github.Repository{Name: github.String("app"), CloneURL: github.String(remote), Size: github.Int(1)}}
+	if err := r.RunTargets(context.Background(), []Target{target}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	if len(sink.data) != 1 || sink.data[0] != "result\n" {
+		t.Fatalf("artifacts not published without an artifacts path: %q", sink.data)
+	}
+}
+
+func TestRunTargetsHookOrder(t *testing.T) {
+	remote := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	trace := filepath.Join(t.TempDir(), "trace")
+	step := func(s string) []string {
+		return []string{"sh", "-c", "sleep 0.2; echo " + s + " >> " + trace}
+	}
+	var scanners []config.Scanner
+	for _, name := range []string{"a", "b"} {
+		scanners = append(scanners, config.Scanner{
+			Name:    name,
+			Command: []string{"sh", "-c", "sleep 0.1; echo scan >> " + trace},
+			Hooks: config.Hooks{
+				BeforeRepo: config.Hook{Command: step("before")},
+				AfterRepo:  config.Hook{Command: step("after")},
+			},
+		})
+	}
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Scanners: scanners, Redactor: secrets.NewRedactor()}
+	r := NewRunner(logger, internalgithub.NewClient(logger, "", "acme"), cfg, Options{ClonePath: t.TempDir()})
+	target := Target{Repo: &github.Repository{Name: github.String("app"), CloneURL: github.String(remote), Size: github.Int(1)}}
+	if err := r.RunTargets(context.Background(), []Target{target}); err != nil {
+		t.Fatalf("run failed: %v", err)
+	}
+	data, err := os.ReadFile(trace)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(strings.Fields(string(data)), " "); got != "before before scan scan after after" {
+		t.Fatalf("hooks overlap with scanners: %s", got)
+	}
+}
+
+func TestRunTargetsRecordsRef(t *testing.T) {
+	remote := gitRepo(t, map[string]string{"main.go": "package main\n"})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+	cfg := &config.Config{Scanners: []config.Scanner{{Name: "noop", Command: []string{"true"}}}, Redactor: secrets.NewRedactor()}
+	for ref, want := range map[string]string{"refs/heads/main": "", "refs/pull/7/head": "refs/pull/7/head"} {
+		sink := &captureSink{}
+		r := NewRunner(logger, internalgithub.NewClient(logger, "", "acme"), cfg, Options{ClonePath: t.TempDir(), Sinks: []report.Sink{sink}})
+		repo := &github.Repository{Name: github.String("app"), CloneURL: github.String(remote), DefaultBranch: github.String("main"), Size: github.Int(1)}
+		if err := r.RunTargets(context.Background(), []Target{{Repo: repo, Ref: ref}}); err != nil {
+			t.Fatalf("run failed: %v", err)
+		}
+		if got := sink.rep.Repos[0]; got.Ref != want || got.OnDefaultBranch() != (want == "") {
+			t.Errorf("%s: recorded ref %q, want %q", ref, got.Ref, want)
+		}
+	}
+}
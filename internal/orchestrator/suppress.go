@@ -0,0 +1,53 @@
+package orchestrator
+
+import (
+	"log/slog"
+	"path/filepath"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+	"github.com/cybrota/eskimo/internal/suppress"
+)
+
+// repoSuppressions returns the central suppressions followed by those of the
+// suppression file committed in the repository, which only apply to it. An
+// invalid repository file is logged and ignored.
+func (r *Runner) repoSuppressions(repo, dir string, now time.Time) suppress.List {
+	list, err := suppress.Load(filepath.Join(dir, suppress.FileName), true)
+	if err != nil {
+		r.logger.Warn("ignoring invalid suppression file", slog.String("repo", repo), slog.Any("error", err))
+		return r.opts.Suppressions
+	}
+	list = list.ForRepo(repo)
+	r.logExpired(list, now)
+	return append(append(suppress.List{}, r.opts.Suppressions...), list...)
+}
+
+// logExpired warns about suppressions whose findings are reported again.
+func (r *Runner) logExpired(list suppress.List, now time.Time) {
+	for _, s := range list.Expired(now) {
+		r.logger.Warn("suppression expired", slog.String("source", s.Source()), slog.String("repo", s.Repo),
+			slog.String("rule", s.Rule), slog.String("fingerprint", s.Fingerprint), slog.String("path", s.Path),
+			slog.String("owner", s.Owner), slog.String("expires", s.Expires))
+	}
+}
+
+// applySuppressions splits list into the findings to report and those
+// hidden by an active suppression.
+func applySuppressions(list []findings.Finding, sup suppress.List, now time.Time) ([]findings.Finding, []report.Suppressed) {
+	if len(sup) == 0 {
+		return list, nil
+	}
+	var kept []findings.Finding
+	var hidden []report.Suppressed
+	for _, f := range list {
+		s, ok := sup.Match(f, now)
+		if !ok {
+			kept = append(kept, f)
+			continue
+		}
+		hidden = append(hidden, report.Suppressed{Finding: f, Reason: s.Reason, Owner: s.Owner, Expires: s.Expires, Source: s.Source()})
+	}
+	return kept, hidden
+}
@@ -0,0 +1,46 @@
+package orchestrator
+
+import (
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/suppress"
+)
+
+func TestRepoSuppressions(t *testing.T) {
+	central, err := suppress.Parse("central", []byte("suppressions:\n  - rule: CVE-1\n    reason: not reachable\n    owner: \"@acme/security\"\n    expires: 2099-01-01\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := t.TempDir()
+	local := "suppressions:\n  - path: testdata/**\n    repo: other\n    reason: fixtures\n    owner: \"@alice\"\n    expires: 2099-01-01\n"
+	if err := os.WriteFile(filepath.Join(dir, suppress.FileName), []byte(local), 0644); err != nil {
+		t.Fatal(err)
+	}
+	r := &Runner{logger: slog.New(slog.NewTextHandler(io.Discard, nil)), opts: Options{Suppressions: central}}
+	now := time.Now()
+	sup := r.repoSuppressions("app", dir, now)
+
+	list := []findings.Finding{
+		{Repo: "app", RuleID: "CVE-1", Path: "go.mod"},
+		{Repo: "app", RuleID: "CVE-2", Path: "testdata/key.pem"},
+		{Repo: "app", RuleID: "CVE-2", Path: "main.go"},
+	}
+	kept, hidden := applySuppressions(list, sup, now)
+	if len(kept) != 1 || kept[0].Path != "main.go" {
+		t.Fatalf("unexpected reported findings %+v", kept)
+	}
+	if len(hidden) != 2 || hidden[0].Owner != "@acme/security" || hidden[1].Reason != "fixtures" {
+		t.Fatalf("unexpected suppressed findings %+v", hidden)
+	}
+
+	// the repository file cannot suppress findings of another repository
+	if _, hidden := applySuppressions([]findings.Finding{{Repo: "other", Path: "testdata/key.pem"}}, sup, now); len(hidden) != 0 {
+		t.Fatalf("repository suppression leaked: %+v", hidden)
+	}
+}
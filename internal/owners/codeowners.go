@@ -0,0 +1,133 @@
+package owners
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Locations are the CODEOWNERS files GitHub reads, in order of precedence.
+var Locations = []string{".github/CODEOWNERS", "CODEOWNERS", "docs/CODEOWNERS"}
+
+type rule struct {
+	pattern string
+	re      *regexp.Regexp
+	owners  []string
+}
+
+// Codeowners maps repository paths to owners as GitHub does: the last
+// matching pattern wins, and a pattern without owners leaves paths unowned.
+type Codeowners struct {
+	rules []rule
+}
+
+// Load reads the first CODEOWNERS file found in the repository at dir. It
+// returns nil when the repository has none.
+func Load(dir string) (*Codeowners, error) {
+	for _, loc := range Locations {
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(loc)))
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		co, err := Parse(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", loc, err)
+		}
+		return co, nil
+	}
+	return nil, nil
+}
+
+// Parse reads CODEOWNERS rules, one pattern and its owners per line.
+func Parse(data []byte) (*Codeowners, error) {
+	co := &Codeowners{}
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; sc.Scan(); n++ {
+		fields := strings.Fields(sc.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		re, err := patternRegexp(fields[0])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", n, err)
+		}
+		r := rule{pattern: fields[0], re: re}
+		for _, owner := range fields[1:] {
+			if strings.HasPrefix(owner, "#") {
+				break
+			}
+			r.owners = append(r.owners, owner)
+		}
+		co.rules = append(co.rules, r)
+	}
+	return co, sc.Err()
+}
+
+// Owners returns the owners of the slash-separated, repository-relative
+// path, or nil when no rule assigns any.
+func (c *Codeowners) Owners(path string) []string {
+	if c == nil {
+		return nil
+	}
+	path = strings.TrimPrefix(path, "/")
+	for i := len(c.rules) - 1; i >= 0; i-- {
+		if c.rules[i].re.MatchString(path) {
+			return c.rules[i].owners
+		}
+	}
+	return nil
+}
+
+// patternRegexp translates a CODEOWNERS pattern. Patterns follow gitignore
+// rules: a pattern with a leading or inner slash is anchored to the root,
+// otherwise it matches at any depth; a matched directory covers everything
+// below it, except for patterns ending in /*, which only cover direct
+// children.
+func patternRegexp(pattern string) (*regexp.Regexp, error) {
+	anchored := strings.Contains(strings.TrimSuffix(pattern, "/"), "/")
+	p := strings.TrimPrefix(pattern, "/")
+	shallow := strings.HasSuffix(p, "/*")
+	p = strings.TrimSuffix(p, "/")
+	if p == "" || p == "*" || p == "**" {
+		return regexp.Compile(`^.*$`)
+	}
+	var b strings.Builder
+	b.WriteString("^")
+	if !anchored {
+		b.WriteString("(?:.*/)?")
+	}
+	for i := 0; i < len(p); i++ {
+		switch c := p[i]; {
+		case strings.HasPrefix(p[i:], "**/"):
+			b.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(p[i:], "/**") && i+3 == len(p):
+			b.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(p[i:], "**"):
+			b.WriteString(".*")
+			i++
+		case c == '*':
+			b.WriteString("[^/]*")
+		case c == '?':
+			b.WriteString("[^/]")
+		case c == '\\' && i+1 < len(p):
+			i++
+			b.WriteString(regexp.QuoteMeta(p[i : i+1]))
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	if !shallow {
+		b.WriteString("(?:/.*)?")
+	}
+	b.WriteString("$")
+	return regexp.Compile(b.String())
+}
@@ -0,0 +1,67 @@
+package owners
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const sample = `# default owners
+*                   @acme/security
+*.js                @acme/frontend # inline comment
+/docs/*             @acme/docs
+apps/               @acme/apps
+/infra/**/prod      @acme/sre @alice
+/infra/modules/**   @acme/platform
+vendor/generated.go
+`
+
+func TestOwners(t *testing.T) {
+	co, err := Parse([]byte(sample))
+	if err != nil {
+		t.Fatal(err)
+	}
+	cases := map[string]string{
+		"main.go":                   "@acme/security",
+		"web/app.js":                "@acme/frontend",
+		"docs/index.md":             "@acme/docs",
+		"docs/guides/setup.md":      "@acme/security",
+		"apps/api/main.go":          "@acme/apps",
+		"services/apps/x.go":        "@acme/apps",
+		"infra/prod/main.tf":        "@acme/sre @alice",
+		"infra/eu/prod/main.tf":     "@acme/sre @alice",
+		"infra/modules/vpc/main.tf": "@acme/platform",
+		"vendor/generated.go":       "",
+		"other/vendor/generated.go": "@acme/security",
+		"/web/components/button.js": "@acme/frontend",
+	}
+	for path, want := range cases {
+		if got := strings.Join(co.Owners(path), " "); got != want {
+			t.Errorf("%s: owners %q, want %q", path, got, want)
+		}
+	}
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	dir := t.TempDir()
+	if co, err := Load(dir); err != nil || co != nil {
+		t.Fatalf("expected no CODEOWNERS, got %v, %v", co, err)
+	}
+	for loc, owner := range map[string]string{"CODEOWNERS": "@root", "docs/CODEOWNERS": "@docs", ".github/CODEOWNERS": "@github"} {
+		path := filepath.Join(dir, filepath.FromSlash(loc))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte("* "+owner+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	co, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := co.Owners("main.go"); len(got) != 1 || got[0] != "@github" {
+		t.Fatalf("expected .github/CODEOWNERS to take precedence, got %v", got)
+	}
+}
@@ -0,0 +1,404 @@
+package report
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"html/template"
+	"io"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+//go:embed report.html.tmpl
+var htmlTemplate string
+
+var htmlTmpl = template.Must(template.New("report").Parse(htmlTemplate))
+
+// topRulesLimit bounds the top rules table of the HTML report.
+const topRulesLimit = 10
+
+// severities lists the severity columns of the reports, most severe first.
+var severities = []findings.Severity{
+	findings.SeverityCritical,
+	findings.SeverityHigh,
+	findings.SeverityMedium,
+	findings.SeverityLow,
+	findings.SeverityInfo,
+	findings.SeverityUnknown,
+}
+
+// History looks up the run preceding a report, returning nil when there is none.
+type History interface {
+	PreviousRun(rep *Report) (*Report, error)
+}
+
+// HTMLSink writes a self-contained HTML report of every run to a file.
+type HTMLSink struct {
+	path    string
+	history History
+}
+
+// NewHTMLSink returns a sink writing to path. history may be nil, in which
+// case the report has no trend against the previous run.
+func NewHTMLSink(path string, history History) *HTMLSink {
+	return &HTMLSink{path: path, history: history}
+}
+
+func (s *HTMLSink) Name() string {
+	return "html"
+}
+
+func (s *HTMLSink) Publish(ctx context.Context, rep *Report) error {
+	var prev *Report
+	if s.history != nil {
+		var err error
+		if prev, err = s.history.PreviousRun(rep); err != nil {
+			return fmt.Errorf("load previous run: %w", err)
+		}
+	}
+	return createFile(s.path, func(w io.Writer) error { return WriteHTML(w, rep, prev) })
+}
+
+type severityCount struct {
+	Severity string
+	Count    int
+	Delta    string
+}
+
+type scannerRow struct {
+	Scanner string
+	Counts  []int
+	Total   int
+	Delta   string
+}
+
+type ownerRow struct {
+	Owner  string
+	Counts []int
+	Total  int
+}
+
+type ruleRow struct {
+	Scanner  string
+	RuleID   string
+	Severity string
+	Count    int
+	Repos    int
+}
+
+type failureRow struct {
+	Repo    string
+	Scanner string
+	Status  string
+	Reason  string
+}
+
+type suppressedRow struct {
+	Repo     string
+	Scanner  string
+	RuleID   string
+	Severity string
+	Location string
+	Reason   string
+	Owner    string
+	Expires  string
+}
+
+type repoRow struct {
+	Name     string
+	SHA      string
+	Counts   []int
+	Total    int
+	Failed   bool
+	Findings []findings.Finding
+}
+
+type htmlData struct {
+	Org         string
+	RunID       string
+	StartedAt   string
+	Duration    string
+	Severities  []string
+	Totals      []severityCount
+	Total       int
+	TotalDelta  string
+	HasPrevious bool
+	PreviousRun string
+	New         int
+	Resolved    int
+	Scanned     int
+	Skipped     int
+	Failed      int
+	Scanners    []scannerRow
+	Owners      []ownerRow
+	TopRules    []ruleRow
+	Failures    []failureRow
+	Suppressed  []suppressedRow
+	Repos       []repoRow
+}
+
+// WriteHTML renders rep as a standalone HTML page. When prev is set, totals
+// are compared against it.
+func WriteHTML(w io.Writer, rep, prev *Report) error {
+	return htmlTmpl.Execute(w, buildHTMLData(rep, prev))
+}
+
+func buildHTMLData(rep, prev *Report) htmlData {
+	all := rep.Findings()
+	d := htmlData{
+		Org:       rep.Org,
+		RunID:     rep.RunID,
+		StartedAt: rep.StartedAt.Format(time.RFC1123),
+		Duration:  rep.FinishedAt.Sub(rep.StartedAt).Round(time.Second).String(),
+		Total:     len(all),
+	}
+	for _, s := range severities {
+		d.Severities = append(d.Severities, s.String())
+	}
+
+	var prevAll []findings.Finding
+	if prev != nil {
+		prevAll = prev.Findings()
+		d.HasPrevious = true
+		d.PreviousRun = prev.RunID
+		d.TotalDelta = delta(len(all), len(prevAll))
+		current, before := fingerprints(all), fingerprints(prevAll)
+		// a finding missing because its repository or scanner failed in
+		// either run is neither new nor resolved
+		completed, prevCompleted := completedScans(rep), completedScans(prev)
+		added := make(map[string]bool)
+		for _, f := range all {
+			key := f.Repo + "/" + f.Scanner
+			if !before[f.Fingerprint] && completed[key] && prevCompleted[key] {
+				added[f.Fingerprint] = true
+			}
+		}
+		d.New = len(added)
+		resolved := make(map[string]bool)
+		for _, f := range prevAll {
+			key := f.Repo + "/" + f.Scanner
+			if !current[f.Fingerprint] && completed[key] && prevCompleted[key] {
+				resolved[f.Fingerprint] = true
+			}
+		}
+		d.Resolved = len(resolved)
+	}
+	bySeverity, prevBySeverity := countBy(all, severityKey), countBy(prevAll, severityKey)
+	for _, s := range severities {
+		c := severityCount{Severity: s.String(), Count: bySeverity[s.String()]}
+		if prev != nil {
+			c.Delta = delta(c.Count, prevBySeverity[s.String()])
+		}
+		d.Totals = append(d.Totals, c)
+	}
+
+	prevByScanner := countBy(prevAll, func(f findings.Finding) string { return f.Scanner })
+	scanners := make(map[string]*scannerRow)
+	for _, f := range all {
+		row, ok := scanners[f.Scanner]
+		if !ok {
+			row = &scannerRow{Scanner: f.Scanner, Counts: make([]int, len(severities))}
+			scanners[f.Scanner] = row
+		}
+		row.Counts[severityIndex(f.Severity)]++
+		row.Total++
+	}
+	for _, row := range scanners {
+		if prev != nil {
+			row.Delta = delta(row.Total, prevByScanner[row.Scanner])
+		}
+		d.Scanners = append(d.Scanners, *row)
+	}
+	sort.Slice(d.Scanners, func(i, j int) bool { return d.Scanners[i].Scanner < d.Scanners[j].Scanner })
+
+	d.Owners = ownerRows(all)
+	d.TopRules = topRules(all)
+
+	for _, sf := range rep.Suppressed() {
+		f := sf.Finding
+		loc := f.Path
+		if f.Line > 0 {
+			loc += ":" + strconv.Itoa(f.Line)
+		}
+		d.Suppressed = append(d.Suppressed, suppressedRow{Repo: f.Repo, Scanner: f.Scanner, RuleID: f.RuleID, Severity: f.Severity.String(),
+			Location: loc, Reason: sf.Reason, Owner: sf.Owner, Expires: sf.Expires})
+	}
+	sort.SliceStable(d.Suppressed, func(i, j int) bool { return d.Suppressed[i].Expires < d.Suppressed[j].Expires })
+
+	for _, repo := range rep.Repos {
+		switch repo.Status {
+		case StatusScanned:
+			d.Scanned++
+		case StatusSkipped:
+			d.Skipped++
+		case StatusCloneFailed:
+			d.Failed++
+			d.Failures = append(d.Failures, failureRow{Repo: repo.Name, Status: repo.Status, Reason: repo.Reason})
+		}
+		for _, scan := range repo.Scans {
+			if scan.Error != "" {
+				d.Failures = append(d.Failures, failureRow{Repo: repo.Name, Scanner: scan.Scanner, Status: "scan_failed", Reason: scan.Error})
+			}
+		}
+		if repo.Status != StatusScanned {
+			continue
+		}
+		row := repoRow{Name: repo.Name, SHA: repo.SHA, Counts: make([]int, len(severities)), Failed: repo.Failed(), Findings: repo.Findings()}
+		for _, f := range row.Findings {
+			row.Counts[severityIndex(f.Severity)]++
+		}
+		row.Total = len(row.Findings)
+		sort.SliceStable(row.Findings, func(i, j int) bool { return row.Findings[i].Severity > row.Findings[j].Severity })
+		d.Repos = append(d.Repos, row)
+	}
+	sort.Slice(d.Repos, func(i, j int) bool {
+		a, b := d.Repos[i], d.Repos[j]
+		for k := range severities {
+			if a.Counts[k] != b.Counts[k] {
+				return a.Counts[k] > b.Counts[k]
+			}
+		}
+		return a.Name < b.Name
+	})
+	return d
+}
+
+// unowned labels findings no owner could be resolved for.
+const unowned = "unowned"
+
+// ownerRows counts findings per owner and severity, the owners with the most
+// severe findings first and unowned findings last.
+func ownerRows(all []findings.Finding) []ownerRow {
+	rows := make(map[string]*ownerRow)
+	for _, f := range all {
+		owner := f.Owner
+		if owner == "" {
+			owner = unowned
+		}
+		row, ok := rows[owner]
+		if !ok {
+			row = &ownerRow{Owner: owner, Counts: make([]int, len(severities))}
+			rows[owner] = row
+		}
+		row.Counts[severityIndex(f.Severity)]++
+		row.Total++
+	}
+	out := make([]ownerRow, 0, len(rows))
+	for _, row := range rows {
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if (a.Owner == unowned) != (b.Owner == unowned) {
+			return b.Owner == unowned
+		}
+		for k := range severities {
+			if a.Counts[k] != b.Counts[k] {
+				return a.Counts[k] > b.Counts[k]
+			}
+		}
+		return a.Owner < b.Owner
+	})
+	return out
+}
+
+func topRules(all []findings.Finding) []ruleRow {
+	type key struct{ scanner, rule string }
+	rows := make(map[key]*ruleRow)
+	repos := make(map[key]map[string]bool)
+	maxSeverity := make(map[key]findings.Severity)
+	for _, f := range all {
+		k := key{f.Scanner, f.RuleID}
+		row, ok := rows[k]
+		if !ok {
+			row = &ruleRow{Scanner: f.Scanner, RuleID: f.RuleID}
+			rows[k] = row
+			repos[k] = make(map[string]bool)
+		}
+		row.Count++
+		repos[k][f.Repo] = true
+		if f.Severity > maxSeverity[k] {
+			maxSeverity[k] = f.Severity
+		}
+	}
+	out := make([]ruleRow, 0, len(rows))
+	for k, row := range rows {
+		row.Repos = len(repos[k])
+		row.Severity = maxSeverity[k].String()
+		out = append(out, *row)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Count != out[j].Count {
+			return out[i].Count > out[j].Count
+		}
+		if out[i].Scanner != out[j].Scanner {
+			return out[i].Scanner < out[j].Scanner
+		}
+		return out[i].RuleID < out[j].RuleID
+	})
+	if len(out) > topRulesLimit {
+		out = out[:topRulesLimit]
+	}
+	return out
+}
+
+func severityKey(f findings.Finding) string {
+	return f.Severity.String()
+}
+
+func severityIndex(s findings.Severity) int {
+	for i, v := range severities {
+		if v == s {
+			return i
+		}
+	}
+	return len(severities) - 1
+}
+
+func countBy(list []findings.Finding, key func(findings.Finding) string) map[string]int {
+	counts := make(map[string]int)
+	for _, f := range list {
+		counts[key(f)]++
+	}
+	return counts
+}
+
+// completedScans returns the repo/scanner pairs of rep that scanned
+// successfully.
+func completedScans(rep *Report) map[string]bool {
+	set := make(map[string]bool)
+	for _, repo := range rep.Repos {
+		if repo.Status != StatusScanned {
+			continue
+		}
+		for _, s := range repo.Scans {
+			if s.Error == "" {
+				set[repo.Name+"/"+s.Scanner] = true
+			}
+		}
+	}
+	return set
+}
+
+func fingerprints(list []findings.Finding) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, f := range list {
+		set[f.Fingerprint] = true
+	}
+	return set
+}
+
+// delta formats the change from before to now, e.g. "+3", "-1" or "±0".
+func delta(now, before int) string {
+	switch d := now - before; {
+	case d > 0:
+		return "+" + strconv.Itoa(d)
+	case d < 0:
+		return strconv.Itoa(d)
+	}
+	return "±0"
+}
@@ -0,0 +1,92 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+func testFinding(repo, scanner, rule string, sev findings.Severity) findings.Finding {
+	f := findings.Finding{Repo: repo, Scanner: scanner, RuleID: rule, Severity: sev, Path: "main.go", Line: 4, Message: "<script>alert(1)</script>"}
+	f.Fingerprint = findings.Fingerprint(f, "")
+	return f
+}
+
+func TestWriteHTML(t *testing.T) {
+	start := time.Date(2025, 1, 2, 0, 0, 0, 0, time.UTC)
+	// only CVE-OLD is resolved: semgrep and the legacy clone failed this run
+	prev := &Report{Org: "acme", RunID: "r1", Repos: []*Repo{{
+		Name: "app", Status: StatusScanned,
+		Scans: []Scan{
+			{Scanner: "trivy", Findings: []findings.Finding{testFinding("app", "trivy", "CVE-OLD", findings.SeverityHigh)}},
+			{Scanner: "semgrep", Findings: []findings.Finding{testFinding("app", "semgrep", "R1", findings.SeverityLow)}},
+		},
+	}, {
+		Name: "legacy", Status: StatusScanned,
+		Scans: []Scan{{Scanner: "trivy", Findings: []findings.Finding{testFinding("legacy", "trivy", "CVE-9", findings.SeverityHigh)}}},
+	}}}
+	rep := &Report{Org: "acme", RunID: "r2", StartedAt: start, FinishedAt: start.Add(90 * time.Second), Repos: []*Repo{
+		{Name: "app", SHA: "abc123", Status: StatusScanned, Scans: []Scan{
+			{Scanner: "trivy", Findings: []findings.Finding{
+				testFinding("app", "trivy", "CVE-1", findings.SeverityCritical),
+				testFinding("app", "trivy", "CVE-2", findings.SeverityHigh),
+			}, Suppressed: []Suppressed{
+				{Finding: testFinding("app", "trivy", "CVE-3", findings.SeverityMedium), Reason: "accepted risk", Owner: "@alice", Expires: "2099-01-01"},
+			}},
+			{Scanner: "semgrep", Error: "exit status 2"},
+		}},
+		{Name: "legacy", Status: StatusCloneFailed, Reason: "auth"},
+	}}
+
+	d := buildHTMLData(rep, prev)
+	if d.Total != 2 || d.New != 2 || d.Resolved != 1 || d.TotalDelta != "-1" {
+		t.Fatalf("unexpected trend: total=%d new=%d resolved=%d delta=%s", d.Total, d.New, d.Resolved, d.TotalDelta)
+	}
+	if len(d.Failures) != 2 {
+		t.Fatalf("expected clone and scan failures, got %+v", d.Failures)
+	}
+	if len(d.Suppressed) != 1 || d.Suppressed[0].Location != "main.go:4" {
+		t.Fatalf("unexpected suppressed findings %+v", d.Suppressed)
+	}
+	if len(d.Owners) != 1 || d.Owners[0].Owner != "unowned" || d.Owners[0].Total != 2 {
+		t.Fatalf("unexpected owners %+v", d.Owners)
+	}
+	if len(d.TopRules) != 2 || d.TopRules[0].RuleID != "CVE-1" {
+		t.Fatalf("unexpected top rules %+v", d.TopRules)
+	}
+
+	var buf bytes.Buffer
+	if err := WriteHTML(&buf, rep, prev); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{"acme", "CVE-1", "sev-critical", "legacy", "exit status 2", "compared with run r1", "1m30s", "accepted risk"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in report", want)
+		}
+	}
+	if strings.Contains(out, "<script>") {
+		t.Errorf("finding message not escaped")
+	}
+}
+
+func TestBuildHTMLDataNewNeedsCompletedScans(t *testing.T) {
+	// the previous trivy scan failed, so its findings are not new
+	prev := &Report{Org: "acme", RunID: "r1", Repos: []*Repo{{
+		Name: "app", Status: StatusScanned,
+		Scans: []Scan{{Scanner: "trivy", Error: "exit status 2"}, {Scanner: "semgrep"}},
+	}}}
+	rep := &Report{Org: "acme", RunID: "r2", Repos: []*Repo{{
+		Name: "app", Status: StatusScanned,
+		Scans: []Scan{
+			{Scanner: "trivy", Findings: []findings.Finding{testFinding("app", "trivy", "CVE-1", findings.SeverityHigh)}},
+			{Scanner: "semgrep", Findings: []findings.Finding{testFinding("app", "semgrep", "R1", findings.SeverityLow)}},
+		},
+	}}}
+	if d := buildHTMLData(rep, prev); d.New != 1 {
+		t.Fatalf("expected only the semgrep finding to be new, got %d", d.New)
+	}
+}
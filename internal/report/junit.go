@@ -0,0 +1,113 @@
+package report
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Name     string       `xml:"name,attr"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Errors   int          `xml:"errors,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     string       `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Errors   int         `xml:"errors,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Failure   *junitMessage `xml:"failure,omitempty"`
+	Error     *junitMessage `xml:"error,omitempty"`
+	Skipped   *junitMessage `xml:"skipped,omitempty"`
+	SystemOut string        `xml:"system-out,omitempty"`
+}
+
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr,omitempty"`
+	Text    string `xml:",chardata"`
+}
+
+// NewJUnitSink writes each run as JUnit XML to path. A scanner test case
+// fails when it reports findings at or above failFrom.
+func NewJUnitSink(path string, failFrom findings.Severity) *FileSink {
+	return &FileSink{name: "junit", path: path, write: func(w io.Writer, rep *Report) error {
+		return WriteJUnit(w, rep, failFrom)
+	}}
+}
+
+// WriteJUnit writes rep as JUnit XML with one test suite per repository and
+// one test case per scanner. Scanner failures are errors, findings at or
+// above failFrom are failures, and the scanner output is the system-out.
+// Scanners whose results are not parsed are skipped rather than passed.
+// Repositories that were not scanned get a single "clone" test case.
+func WriteJUnit(w io.Writer, rep *Report, failFrom findings.Severity) error {
+	doc := junitSuites{Name: "eskimo " + rep.Org, Time: fmt.Sprintf("%.3f", rep.FinishedAt.Sub(rep.StartedAt).Seconds())}
+	for _, repo := range rep.Repos {
+		suite := junitSuite{Name: repo.Name}
+		switch repo.Status {
+		case StatusSkipped:
+			suite.Cases = append(suite.Cases, junitCase{Name: "clone", Classname: repo.Name, Skipped: &junitMessage{Message: repo.Reason}})
+			suite.Skipped++
+		case StatusCloneFailed:
+			suite.Cases = append(suite.Cases, junitCase{Name: "clone", Classname: repo.Name, Error: &junitMessage{Message: "clone failed: " + repo.Reason, Type: repo.Reason}})
+			suite.Errors++
+		}
+		for _, scan := range repo.Scans {
+			c := junitCase{Name: scan.Scanner, Classname: repo.Name, SystemOut: scan.Output}
+			failing := findings.Filter(scan.Findings, failFrom)
+			switch {
+			case scan.Error != "":
+				c.Error = &junitMessage{Message: scan.Error, Type: "scanner_error"}
+				suite.Errors++
+			case !scan.Parsed():
+				c.Skipped = &junitMessage{Message: "results not parsed, see system-out"}
+				suite.Skipped++
+			case len(failing) > 0:
+				var b strings.Builder
+				for _, f := range failing {
+					fmt.Fprintf(&b, "[%s] %s %s", f.Severity, f.RuleID, f.Path)
+					if f.Line > 0 {
+						fmt.Fprintf(&b, ":%d", f.Line)
+					}
+					fmt.Fprintf(&b, " %s\n", f.Message)
+				}
+				c.Failure = &junitMessage{Message: fmt.Sprintf("%d findings at or above %s", len(failing), failFrom), Type: "findings", Text: b.String()}
+				suite.Failures++
+			}
+			suite.Cases = append(suite.Cases, c)
+		}
+		suite.Tests = len(suite.Cases)
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
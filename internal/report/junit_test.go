@@ -0,0 +1,47 @@
+package report
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+func TestWriteJUnit(t *testing.T) {
+	rep := &Report{Org: "acme", Repos: []*Repo{
+		{Name: "app", Status: StatusScanned, Scans: []Scan{
+			{Scanner: "trivy", Format: "sarif", Output: "scanned 3 files", Findings: []findings.Finding{testFinding("app", "trivy", "CVE-1", findings.SeverityCritical)}},
+			{Scanner: "semgrep", Format: "sarif", Findings: []findings.Finding{testFinding("app", "semgrep", "R1", findings.SeverityLow)}},
+			{Scanner: "checkov", Error: "exit status 2"},
+			{Scanner: "scharf", Output: "{}"},
+		}},
+		{Name: "old", Status: StatusSkipped, Reason: "archived"},
+		{Name: "legacy", Status: StatusCloneFailed, Reason: "auth"},
+	}}
+	var buf bytes.Buffer
+	if err := WriteJUnit(&buf, rep, findings.SeverityHigh); err != nil {
+		t.Fatal(err)
+	}
+	var doc junitSuites
+	if err := xml.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatalf("invalid XML: %v\n%s", err, buf.String())
+	}
+	if doc.Tests != 6 || doc.Failures != 1 || doc.Errors != 2 || doc.Skipped != 2 {
+		t.Fatalf("unexpected totals tests=%d failures=%d errors=%d skipped=%d", doc.Tests, doc.Failures, doc.Errors, doc.Skipped)
+	}
+	app := doc.Suites[0]
+	if app.Name != "app" || len(app.Cases) != 4 {
+		t.Fatalf("unexpected suite %+v", app)
+	}
+	trivy, semgrep := app.Cases[0], app.Cases[1]
+	if trivy.Failure == nil || trivy.SystemOut != "scanned 3 files" {
+		t.Errorf("expected trivy failure with output, got %+v", trivy)
+	}
+	if semgrep.Failure != nil {
+		t.Errorf("low finding below threshold failed the case")
+	}
+	if scharf := app.Cases[3]; scharf.Skipped == nil {
+		t.Errorf("unparsed scanner passed: %+v", scharf)
+	}
+}
@@ -0,0 +1,188 @@
+package report
+
+import (
+	"context"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+// Report describes the outcome of a single eskimo run. ArtifactsDir is the
+// run directory holding scanner result files; artifact paths of a Scan are
+// relative to it.
+type Report struct {
+	Org          string    `json:"org"`
+	RunID        string    `json:"run_id"`
+	StartedAt    time.Time `json:"started_at"`
+	FinishedAt   time.Time `json:"finished_at"`
+	ArtifactsDir string    `json:"artifacts_dir,omitempty"`
+	Repos        []*Repo   `json:"repos"`
+}
+
+// Repository statuses.
+const (
+	StatusScanned     = "scanned"
+	StatusSkipped     = "skipped"
+	StatusCloneFailed = "clone_failed"
+)
+
+// Repo holds the scan results for one repository. Reason explains a status
+// other than StatusScanned, such as the clone failure class or the repository
+// state (archived, disabled, empty, locked) that caused it to be skipped;
+// locked repositories are only detected by their refused clone.
+// Ref is set when the scanned commit is not on the default branch.
+type Repo struct {
+	Name   string     `json:"name"`
+	SHA    string     `json:"sha,omitempty"`
+	Ref    string     `json:"ref,omitempty"`
+	Status string     `json:"status"`
+	Reason string     `json:"reason,omitempty"`
+	Clone  *CloneInfo `json:"clone,omitempty"`
+	Scans  []Scan     `json:"scans"`
+	Dedup  []Dedup    `json:"dedup,omitempty"`
+}
+
+// Dedup records findings of several scanners that were merged into one
+// canonical finding, identified by fingerprint.
+type Dedup struct {
+	Canonical  string        `json:"canonical"`
+	Scanner    string        `json:"scanner"`
+	RuleID     string        `json:"rule_id"`
+	Duplicates []DedupMember `json:"duplicates"`
+}
+
+// DedupMember is a finding merged into a canonical one and why.
+type DedupMember struct {
+	Fingerprint string `json:"fingerprint"`
+	Scanner     string `json:"scanner"`
+	RuleID      string `json:"rule_id"`
+	Reason      string `json:"reason"`
+}
+
+// CloneInfo records how a repository was checked out for scanning.
+type CloneInfo struct {
+	Strategy   string `json:"strategy"`
+	History    string `json:"history"`
+	Submodules bool   `json:"submodules"`
+	LFS        string `json:"lfs"`
+}
+
+// Scan holds the result of one scanner against one repository. Suppressed
+// findings and Duplicates, merged into a finding of another scanner, are
+// kept apart from Findings and do not count towards them. Format is the
+// scanner's result format; see Parsed.
+type Scan struct {
+	Scanner    string             `json:"scanner"`
+	Format     string             `json:"format,omitempty"`
+	Error      string             `json:"error,omitempty"`
+	Output     string             `json:"-"`
+	Artifacts  []string           `json:"artifacts,omitempty"`
+	Findings   []findings.Finding `json:"findings,omitempty"`
+	Suppressed []Suppressed       `json:"suppressed,omitempty"`
+	Duplicates []findings.Finding `json:"duplicates,omitempty"`
+}
+
+// Parsed reports whether the scanner's results were parsed into findings.
+// Only SARIF is; for other scanners Findings stays empty whatever they found,
+// and their results are only in Output and Artifacts.
+func (s Scan) Parsed() bool {
+	return s.Format == "sarif"
+}
+
+// Suppressed is a finding hidden by a suppression file entry, with the
+// reason, owner and expiry date of that entry and the file it came from.
+type Suppressed struct {
+	Finding findings.Finding `json:"finding"`
+	Reason  string           `json:"reason"`
+	Owner   string           `json:"owner"`
+	Expires string           `json:"expires"`
+	Source  string           `json:"source"`
+}
+
+// Sink receives the report once a run finishes.
+type Sink interface {
+	Name() string
+	Publish(ctx context.Context, rep *Report) error
+}
+
+// Repo returns the entry for name, creating it if needed.
+func (r *Report) Repo(name string) *Repo {
+	for _, repo := range r.Repos {
+		if repo.Name == name {
+			return repo
+		}
+	}
+	repo := &Repo{Name: name}
+	r.Repos = append(r.Repos, repo)
+	return repo
+}
+
+// Findings returns all findings of the repository.
+func (r *Repo) Findings() []findings.Finding {
+	var out []findings.Finding
+	for _, s := range r.Scans {
+		out = append(out, s.Findings...)
+	}
+	return out
+}
+
+// Suppressed returns all suppressed findings of the repository.
+func (r *Repo) Suppressed() []Suppressed {
+	var out []Suppressed
+	for _, s := range r.Scans {
+		out = append(out, s.Suppressed...)
+	}
+	return out
+}
+
+// OnDefaultBranch reports whether the scanned commit belongs to the default
+// branch. Findings of other commits, such as pull request heads, must not
+// change the tracked state of the repository.
+func (r *Repo) OnDefaultBranch() bool {
+	return r.Ref == ""
+}
+
+// Failed reports whether any scanner failed for the repository.
+func (r *Repo) Failed() bool {
+	for _, s := range r.Scans {
+		if s.Error != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultBranch returns the report restricted to repositories scanned on
+// their default branch. It returns r itself when no repository is excluded.
+func (r *Report) DefaultBranch() *Report {
+	var repos []*Repo
+	for _, repo := range r.Repos {
+		if repo.OnDefaultBranch() {
+			repos = append(repos, repo)
+		}
+	}
+	if len(repos) == len(r.Repos) {
+		return r
+	}
+	out := *r
+	out.Repos = repos
+	return &out
+}
+
+// Findings returns all findings of the run.
+func (r *Report) Findings() []findings.Finding {
+	var out []findings.Finding
+	for _, repo := range r.Repos {
+		out = append(out, repo.Findings()...)
+	}
+	return out
+}
+
+// Suppressed returns all suppressed findings of the run.
+func (r *Report) Suppressed() []Suppressed {
+	var out []Suppressed
+	for _, repo := range r.Repos {
+		out = append(out, repo.Suppressed()...)
+	}
+	return out
+}
@@ -0,0 +1,182 @@
+package report
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// FileSink writes the report to a file in some format.
+type FileSink struct {
+	name  string
+	path  string
+	write func(io.Writer, *Report) error
+}
+
+// NewMarkdownSink writes a Markdown summary of each run to path.
+func NewMarkdownSink(path string) *FileSink {
+	return &FileSink{name: "summary-md", path: path, write: WriteMarkdown}
+}
+
+// NewCSVSink writes the findings of each run as CSV to path.
+func NewCSVSink(path string) *FileSink {
+	return &FileSink{name: "summary-csv", path: path, write: WriteCSV}
+}
+
+func (s *FileSink) Name() string {
+	return s.name
+}
+
+func (s *FileSink) Publish(ctx context.Context, rep *Report) error {
+	return createFile(s.path, func(w io.Writer) error { return s.write(w, rep) })
+}
+
+func createFile(path string, write func(io.Writer) error) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := write(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// WriteMarkdown writes a table of findings per repository and severity, one
+// per owner, and the repositories that failed to clone or scan. Suppressed
+// findings are only counted in the headline.
+func WriteMarkdown(w io.Writer, rep *Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## Eskimo scan summary for %s\n\n", mdEscape(rep.Org))
+	fmt.Fprintf(&b, "Run `%s`: %d findings in %d repositories", rep.RunID, len(rep.Findings()), len(rep.Repos))
+	if n := len(rep.Suppressed()); n > 0 {
+		fmt.Fprintf(&b, ", %d suppressed", n)
+	}
+	b.WriteString(".\n\n")
+	mdHeader(&b, "Repository", "Status")
+
+	repos := append([]*Repo(nil), rep.Repos...)
+	sort.Slice(repos, func(i, j int) bool { return repos[i].Name < repos[j].Name })
+	totals := make([]int, len(severities))
+	for _, repo := range repos {
+		counts := make([]int, len(severities))
+		list := repo.Findings()
+		for _, f := range list {
+			counts[severityIndex(f.Severity)]++
+			totals[severityIndex(f.Severity)]++
+		}
+		status := repo.Status
+		if repo.Failed() {
+			status += " (scanner failed)"
+		}
+		fmt.Fprintf(&b, "| %s | %s |", mdEscape(repo.Name), status)
+		for i := range severities {
+			fmt.Fprintf(&b, " %d |", counts[i])
+		}
+		fmt.Fprintf(&b, " %d |\n", len(list))
+	}
+	b.WriteString("| **Total** | |")
+	for i := range severities {
+		fmt.Fprintf(&b, " **%d** |", totals[i])
+	}
+	fmt.Fprintf(&b, " **%d** |\n", len(rep.Findings()))
+
+	if owners := ownerRows(rep.Findings()); len(owners) > 0 {
+		b.WriteString("\n### Findings by owner\n\n")
+		mdHeader(&b, "Owner")
+		for _, row := range owners {
+			fmt.Fprintf(&b, "| %s |", mdEscape(row.Owner))
+			for _, n := range row.Counts {
+				fmt.Fprintf(&b, " %d |", n)
+			}
+			fmt.Fprintf(&b, " %d |\n", row.Total)
+		}
+	}
+
+	var failures []string
+	for _, repo := range repos {
+		if repo.Status == StatusCloneFailed {
+			failures = append(failures, fmt.Sprintf("- %s: clone failed (%s)", mdEscape(repo.Name), repo.Reason))
+		}
+		for _, scan := range repo.Scans {
+			if scan.Error != "" {
+				failures = append(failures, fmt.Sprintf("- %s: %s failed: %s", mdEscape(repo.Name), mdEscape(scan.Scanner), mdEscape(scan.Error)))
+			}
+		}
+	}
+	if len(failures) > 0 {
+		b.WriteString("\n### Failures\n\n" + strings.Join(failures, "\n") + "\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// mdHeader writes the header of a table with the given leading columns
+// followed by one column per severity and a total.
+func mdHeader(b *strings.Builder, columns ...string) {
+	b.WriteString("|")
+	for _, c := range columns {
+		fmt.Fprintf(b, " %s |", c)
+	}
+	for _, s := range severities {
+		fmt.Fprintf(b, " %s |", strings.ToUpper(s.String()[:1])+s.String()[1:])
+	}
+	b.WriteString(" Total |\n|")
+	for range columns {
+		b.WriteString("---|")
+	}
+	for range severities {
+		b.WriteString("--:|")
+	}
+	b.WriteString("--:|\n")
+}
+
+// mdEscape keeps text from breaking out of a Markdown table cell.
+func mdEscape(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "|", `\|`)
+}
+
+// WriteCSV writes one row per finding, grouped by repository and most severe
+// first within each.
+func WriteCSV(w io.Writer, rep *Report) error {
+	list := rep.Findings()
+	sort.SliceStable(list, func(i, j int) bool {
+		if list[i].Repo != list[j].Repo {
+			return list[i].Repo < list[j].Repo
+		}
+		return list[i].Severity > list[j].Severity
+	})
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"repo", "scanner", "rule", "severity", "file", "line", "owner"})
+	// owners come from CODEOWNERS in the scanned repository, so they are
+	// escaped like scanner output even though handles such as @org/team
+	// then read '@org/team
+	for _, f := range list {
+		line := ""
+		if f.Line > 0 {
+			line = strconv.Itoa(f.Line)
+		}
+		cw.Write([]string{csvCell(f.Repo), csvCell(f.Scanner), csvCell(f.RuleID), f.Severity.String(), csvCell(f.Path), line, csvCell(f.Owner)})
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// csvCell prevents spreadsheet formula injection from scanner-controlled text.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
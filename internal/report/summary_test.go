@@ -0,0 +1,65 @@
+package report
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+func summaryReport() *Report {
+	f := testFinding("app", "trivy", "CVE-1", findings.SeverityCritical)
+	f.Owner = "@acme/platform"
+	formula := testFinding("app", "semgrep", "=HYPERLINK(\"x\")", findings.SeverityLow)
+	return &Report{Org: "acme", RunID: "r1", Repos: []*Repo{
+		{Name: "app", Status: StatusScanned, Scans: []Scan{
+			{Scanner: "trivy", Findings: []findings.Finding{f}, Suppressed: []Suppressed{
+				{Finding: testFinding("app", "trivy", "CVE-3", findings.SeverityHigh), Reason: "not reachable", Owner: "@alice", Expires: "2099-01-01"},
+			}},
+			{Scanner: "semgrep", Findings: []findings.Finding{formula}},
+		}},
+		{Name: "lib|legacy", Status: StatusCloneFailed, Reason: "auth"},
+	}}
+}
+
+func TestWriteMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, summaryReport()); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"2 findings in 2 repositories, 1 suppressed.",
+		"| Repository | Status | Critical | High | Medium | Low | Info | Unknown | Total |",
+		"| app | scanned | 1 | 0 | 0 | 1 | 0 | 0 | 2 |",
+		"| **Total** | | **1** |",
+		`lib\|legacy: clone failed (auth)`,
+		"| Owner | Critical | High | Medium | Low | Info | Unknown | Total |",
+		"| @acme/platform | 1 | 0 | 0 | 0 | 0 | 0 | 1 |\n| unowned | 0 | 0 | 0 | 1 | 0 | 0 | 1 |",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected %q in\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCSV(&buf, summaryReport()); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("expected header and 2 rows, got %q", lines)
+	}
+	if lines[0] != "repo,scanner,rule,severity,file,line,owner" {
+		t.Errorf("unexpected header %q", lines[0])
+	}
+	if lines[1] != "app,trivy,CVE-1,critical,main.go,4,'@acme/platform" {
+		t.Errorf("unexpected row %q", lines[1])
+	}
+	if !strings.Contains(lines[2], `"'=HYPERLINK(""x"")"`) {
+		t.Errorf("formula not neutralized: %q", lines[2])
+	}
+}
@@ -0,0 +1,60 @@
+package sandbox
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// childArg marks a re-executed eskimo process that applies the sandbox
+// restrictions to itself before executing the scanner.
+const childArg = "__eskimo_sandbox"
+
+// Policy describes the restrictions applied to a sandboxed process.
+// Zero limits are not applied.
+type Policy struct {
+	CPUSeconds     uint64
+	MemoryBytes    uint64
+	FileSizeBytes  uint64
+	DisableNetwork bool
+}
+
+// BaseEnv lists the variables a sandboxed process inherits in addition to
+// those it declares.
+var BaseEnv = []string{"PATH", "HOME", "USER", "LANG", "LC_ALL", "TMPDIR", "TZ"}
+
+// HandleChild runs the sandbox helper when the process was started by
+// Command. It must be the first call in main; it returns immediately
+// otherwise and never returns in the helper.
+func HandleChild() {
+	if len(os.Args) < 4 || os.Args[1] != childArg || os.Args[3] != "--" {
+		return
+	}
+	limits, err := decodeLimits(os.Args[2])
+	if err == nil {
+		err = execChild(limits, os.Args[4:])
+	}
+	fmt.Fprintf(os.Stderr, "eskimo sandbox: %v\n", err)
+	os.Exit(126)
+}
+
+func encodeLimits(p Policy) string {
+	return fmt.Sprintf("%d,%d,%d", p.CPUSeconds, p.MemoryBytes, p.FileSizeBytes)
+}
+
+func decodeLimits(s string) (Policy, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 3 {
+		return Policy{}, fmt.Errorf("malformed limits %q", s)
+	}
+	var vals [3]uint64
+	for i, part := range parts {
+		v, err := strconv.ParseUint(part, 10, 64)
+		if err != nil {
+			return Policy{}, fmt.Errorf("malformed limits %q: %w", s, err)
+		}
+		vals[i] = v
+	}
+	return Policy{CPUSeconds: vals[0], MemoryBytes: vals[1], FileSizeBytes: vals[2]}, nil
+}
@@ -0,0 +1,76 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"syscall"
+)
+
+const prSetNoNewPrivs = 38
+
+// Command returns a command that runs argv inside the sandbox: in its own
+// process group, with resource limits and no-new-privileges applied by a
+// re-executed eskimo helper, and optionally in an empty network namespace.
+func Command(ctx context.Context, p Policy, argv []string) (*exec.Cmd, error) {
+	if len(argv) == 0 {
+		return nil, errors.New("no command specified")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locate eskimo binary: %w", err)
+	}
+	args := append([]string{childArg, encodeLimits(p), "--"}, argv...)
+	cmd := exec.CommandContext(ctx, self, args...)
+	attr := &syscall.SysProcAttr{Setpgid: true, Pdeathsig: syscall.SIGKILL}
+	if p.DisableNetwork {
+		// an unprivileged user namespace lets us create a network namespace
+		// that only has a loopback interface
+		attr.Cloneflags = syscall.CLONE_NEWUSER | syscall.CLONE_NEWNET
+		attr.UidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getuid(), HostID: os.Getuid(), Size: 1}}
+		attr.GidMappings = []syscall.SysProcIDMap{{ContainerID: os.Getgid(), HostID: os.Getgid(), Size: 1}}
+	}
+	cmd.SysProcAttr = attr
+	// kill the whole process group so scanner children do not outlive it
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+	return cmd, nil
+}
+
+func execChild(p Policy, argv []string) error {
+	if len(argv) == 0 {
+		return errors.New("no command specified")
+	}
+	limits := []struct {
+		resource int
+		value    uint64
+	}{
+		{syscall.RLIMIT_CPU, p.CPUSeconds},
+		{syscall.RLIMIT_AS, p.MemoryBytes},
+		{syscall.RLIMIT_FSIZE, p.FileSizeBytes},
+	}
+	for _, l := range limits {
+		if l.value == 0 {
+			continue
+		}
+		if err := syscall.Setrlimit(l.resource, &syscall.Rlimit{Cur: l.value, Max: l.value}); err != nil {
+			return fmt.Errorf("setrlimit %d: %w", l.resource, err)
+		}
+	}
+	// no_new_privs is per thread and inherited across execve from the calling thread
+	runtime.LockOSThread()
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_PRCTL, prSetNoNewPrivs, 1, 0); errno != 0 {
+		return fmt.Errorf("prctl no_new_privs: %w", errno)
+	}
+	path, err := exec.LookPath(argv[0])
+	if err != nil {
+		return err
+	}
+	return syscall.Exec(path, argv, os.Environ())
+}
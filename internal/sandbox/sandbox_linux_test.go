@@ -0,0 +1,57 @@
+//go:build linux
+
+package sandbox
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestMain(m *testing.M) {
+	HandleChild()
+	os.Exit(m.Run())
+}
+
+func TestCommandAppliesLimits(t *testing.T) {
+	cmd, err := Command(context.Background(), Policy{CPUSeconds: 7}, []string{"sh", "-c", "ulimit -t; grep NoNewPrivs /proc/self/status"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("run: %v: %s", err, out)
+	}
+	lines := strings.Fields(string(out))
+	if len(lines) < 3 || lines[0] != "7" || lines[2] != "1" {
+		t.Fatalf("limits not applied: %q", out)
+	}
+}
+
+func TestDecodeLimits(t *testing.T) {
+	p := Policy{CPUSeconds: 1, MemoryBytes: 2, FileSizeBytes: 3}
+	got, err := decodeLimits(encodeLimits(p))
+	if err != nil || got != p {
+		t.Fatalf("round trip failed: %+v, %v", got, err)
+	}
+	if _, err := decodeLimits("1,2"); err == nil {
+		t.Fatalf("expected error for malformed limits")
+	}
+}
+
+func TestCommandDisablesNetwork(t *testing.T) {
+	cmd, err := Command(context.Background(), Policy{DisableNetwork: true}, []string{"cat", "/proc/net/dev"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Skipf("user namespaces unavailable: %v", err)
+	}
+	for _, line := range strings.Split(string(out), "\n")[2:] {
+		if name, _, ok := strings.Cut(strings.TrimSpace(line), ":"); ok && name != "lo" {
+			t.Fatalf("unexpected interface %q in sandbox", name)
+		}
+	}
+}
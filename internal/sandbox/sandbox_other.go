@@ -0,0 +1,19 @@
+//go:build !linux
+
+package sandbox
+
+import (
+	"context"
+	"errors"
+	"os/exec"
+)
+
+var errUnsupported = errors.New("scanner sandbox is only supported on Linux")
+
+func Command(ctx context.Context, p Policy, argv []string) (*exec.Cmd, error) {
+	return nil, errUnsupported
+}
+
+func execChild(p Policy, argv []string) error {
+	return errUnsupported
+}
@@ -1,54 +1,368 @@
 package scanner
 
 import (
+	"bytes"
 	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/sandbox"
 )
 
 // Scanner defines a pluggable scanner
 
 type Scanner struct {
-	Name       string
-	PreCommand []string
-	Command    []string
-	EnvVars    []string
-	Disable    bool
+	Name         string
+	PreCommand   []string
+	Hooks        config.Hooks
+	Command      []string
+	EnvVars      config.EnvList
+	Format       string
+	Severity     config.SeverityPolicy
+	Outputs      []string
+	NeedsHistory bool
+	HistorySince string
+	Image        string
+	Runtime      string
+	Sandbox      config.Sandbox
+	InheritEnv   *bool
+	Disable      bool
+	Secrets      map[string]string
+}
+
+const (
+	// containerSrc is where the repository is mounted inside scanner containers.
+	containerSrc = "/src"
+	// containerOut is the scratch output directory inside scanner containers.
+	containerOut = "/out"
+	// defaultRuntime is used when a containerized scanner sets no runtime.
+	defaultRuntime = "docker"
+)
+
+// Result holds the output of a scanner run. Stdout is kept separately from
+// the combined output so structured results can be parsed from it. Artifacts
+// lists the files left in the output directory, relative to it.
+type Result struct {
+	Output    []byte
+	Stdout    []byte
+	Artifacts []string
 }
 
 func (s Scanner) Run(ctx context.Context, repoPath string) ([]byte, error) {
-	env := s.buildEnv()
+	res, err := s.Scan(ctx, config.TemplateVars{Path: repoPath})
+	return res.Output, err
+}
+
+// Scan runs the scanner against the repository at vars.Path with the command
+// arguments expanded from vars. Result files are collected in vars.OutputDir,
+// also exposed as ESKIMO_OUTPUT_DIR; when it is empty a temporary directory
+// is used and discarded.
+func (s Scanner) Scan(ctx context.Context, vars config.TemplateVars) (Result, error) {
 	if len(s.Command) == 0 {
-		return nil, fmt.Errorf("no command specified")
+		return Result{}, fmt.Errorf("no command specified")
+	}
+	repoPath, outDir := vars.Path, vars.OutputDir
+	keep := outDir != ""
+	if !keep {
+		tmp, err := os.MkdirTemp("", "eskimo-"+s.Name+"-")
+		if err != nil {
+			return Result{}, fmt.Errorf("create scanner output dir: %w", err)
+		}
+		defer os.RemoveAll(tmp)
+		outDir = tmp
+	}
+	outDir, err := filepath.Abs(outDir)
+	if err != nil {
+		return Result{}, fmt.Errorf("resolve scanner output dir: %w", err)
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		return Result{}, fmt.Errorf("create scanner output dir: %w", err)
+	}
+	env := append(s.buildEnv(), "ESKIMO_OUTPUT_DIR="+outDir)
+	vars.OutputDir = outDir
+	var argv []string
+	if s.Image != "" {
+		argv, err = s.containerArgs(vars)
+	} else {
+		argv, err = expandArgs(s.Command, vars)
+	}
+	if err != nil {
+		return Result{}, err
+	}
+	cmd, err := s.command(ctx, argv)
+	if err != nil {
+		return Result{}, err
 	}
-	cmd := exec.CommandContext(ctx, s.Command[0], s.Command[1:]...)
 	cmd.Env = env
 	cmd.Dir = repoPath
-	out, err := cmd.CombinedOutput()
-	return out, err
+	var stdout bytes.Buffer
+	combined := &lockedBuffer{}
+	cmd.Stdout = io.MultiWriter(combined, &stdout)
+	cmd.Stderr = combined
+	err = cmd.Run()
+	res := Result{Output: combined.Bytes(), Stdout: stdout.Bytes()}
+	if !keep {
+		return res, err
+	}
+	// scanners often exit non-zero when they report findings, so result
+	// files are collected either way
+	artifacts, collectErr := s.collectOutputs(repoPath, outDir)
+	res.Artifacts = artifacts
+	if err == nil {
+		err = collectErr
+	}
+	return res, err
+}
+
+// expandArgs executes every argument as a template over vars.
+func expandArgs(argv []string, vars config.TemplateVars) ([]string, error) {
+	out := make([]string, len(argv))
+	for i, arg := range argv {
+		if !strings.Contains(arg, "{{") {
+			out[i] = arg
+			continue
+		}
+		tmpl, err := config.ParseArg(arg)
+		if err != nil {
+			return nil, fmt.Errorf("command argument %q: %w", arg, err)
+		}
+		var b strings.Builder
+		if err := tmpl.Execute(&b, vars); err != nil {
+			return nil, fmt.Errorf("command argument %q: %w", arg, err)
+		}
+		out[i] = b.String()
+	}
+	return out, nil
+}
+
+// collectOutputs copies the regular files matching the outputs globs from
+// the repository into outDir and lists everything outDir holds. Symlinks are
+// ignored so a repository cannot smuggle files from the host into artifacts.
+func (s Scanner) collectOutputs(repoPath, outDir string) ([]string, error) {
+	for _, pattern := range s.Outputs {
+		matches, err := filepath.Glob(filepath.Join(repoPath, pattern))
+		if err != nil {
+			return nil, fmt.Errorf("outputs pattern %q: %w", pattern, err)
+		}
+		for _, m := range matches {
+			info, err := os.Lstat(m)
+			if err != nil || !info.Mode().IsRegular() {
+				continue
+			}
+			rel, err := filepath.Rel(repoPath, m)
+			if err != nil {
+				return nil, err
+			}
+			if err := copyFile(m, filepath.Join(outDir, rel)); err != nil {
+				return nil, fmt.Errorf("collect output %s: %w", rel, err)
+			}
+		}
+	}
+	var files []string
+	err := filepath.WalkDir(outDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || !d.Type().IsRegular() {
+			return err
+		}
+		rel, err := filepath.Rel(outDir, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+func copyFile(src, dst string) error {
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func (s Scanner) command(ctx context.Context, argv []string) (*exec.Cmd, error) {
+	if !s.Sandbox.Enabled {
+		return exec.CommandContext(ctx, argv[0], argv[1:]...), nil
+	}
+	const mb = 1 << 20
+	return sandbox.Command(ctx, sandbox.Policy{
+		CPUSeconds:     s.Sandbox.CPUSeconds,
+		MemoryBytes:    s.Sandbox.MemoryMB * mb,
+		FileSizeBytes:  s.Sandbox.FileSizeMB * mb,
+		DisableNetwork: s.Sandbox.DisableNetwork,
+	}, argv)
+}
+
+// containerArgs wraps the scanner command in a container run. The repository
+// at vars.Path is mounted read-only at /src and vars.OutputDir writable at
+// /out; the command arguments see these container paths.
+func (s Scanner) containerArgs(vars config.TemplateVars) ([]string, error) {
+	src, outDir := vars.Path, vars.OutputDir
+	vars.Path, vars.OutputDir = containerSrc, containerOut
+	command, err := expandArgs(s.Command, vars)
+	if err != nil {
+		return nil, err
+	}
+	return s.containerRun(src, true, []string{
+		"-v", outDir + ":" + containerOut,
+		"-e", "ESKIMO_OUTPUT_DIR=" + containerOut,
+	}, command)
+}
+
+// containerRun returns the runtime invocation running command in the
+// scanner image with dir mounted at /src, read-only when readOnly is set.
+// extra arguments are passed to the runtime before the image. Environment
+// variables are passed by name so values stay out of argv.
+func (s Scanner) containerRun(dir string, readOnly bool, extra, command []string) ([]string, error) {
+	src, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("resolve repository path: %w", err)
+	}
+	runtime := s.Runtime
+	if runtime == "" {
+		runtime = defaultRuntime
+	}
+	mount := src + ":" + containerSrc
+	if readOnly {
+		mount += ":ro"
+	}
+	args := []string{runtime, "run", "--rm",
+		"--security-opt", "no-new-privileges",
+		"-v", mount,
+		"-w", containerSrc,
+	}
+	args = append(args, extra...)
+	if uid := os.Getuid(); uid >= 0 {
+		args = append(args, "--user", fmt.Sprintf("%d:%d", uid, os.Getgid()))
+	}
+	for _, entry := range s.EnvVars {
+		key, _, _ := strings.Cut(entry, "=")
+		args = append(args, "-e", key)
+	}
+	for _, key := range s.secretNames() {
+		args = append(args, "-e", key)
+	}
+	args = append(args, s.Image)
+	return append(args, command...), nil
 }
 
 func (s Scanner) RunPreCommand(ctx context.Context, workDir string) ([]byte, error) {
-	if len(s.PreCommand) == 0 {
+	out, err := s.runCommand(ctx, s.PreCommand, workDir)
+	if err != nil {
+		return out, fmt.Errorf("pre-command failed: %w", err)
+	}
+	return out, nil
+}
+
+// RunHook runs a lifecycle hook in workDir with the scanner environment and
+// under the same isolation as the scanner itself: in its sandbox, and in its
+// image when it is containerized. Hooks prepare the checkout, so workDir is
+// mounted writable. A hook without a command is a no-op.
+func (s Scanner) RunHook(ctx context.Context, h config.Hook, workDir string) ([]byte, error) {
+	return s.runCommand(ctx, h.Command, workDir)
+}
+
+func (s Scanner) runCommand(ctx context.Context, argv []string, workDir string) ([]byte, error) {
+	if len(argv) == 0 {
 		return nil, nil
 	}
-	env := s.buildEnv()
-	pre := exec.CommandContext(ctx, s.PreCommand[0], s.PreCommand[1:]...)
-	pre.Dir = workDir
-	pre.Env = env
-	out, err := pre.CombinedOutput()
+	if s.Image != "" {
+		var err error
+		if argv, err = s.containerRun(workDir, false, nil, argv); err != nil {
+			return nil, err
+		}
+	}
+	cmd, err := s.command(ctx, argv)
 	if err != nil {
-		return out, fmt.Errorf("pre-command failed: %v: %s", err, string(out))
+		return nil, err
+	}
+	cmd.Dir = workDir
+	cmd.Env = s.buildEnv()
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return out, fmt.Errorf("%v: %s", err, string(out))
 	}
 	return out, nil
 }
 
+// buildEnv returns the scanner environment: eskimo's environment (unless
+// inherit_env is false or the scanner is sandboxed) plus the declared entries.
 func (s Scanner) buildEnv() []string {
-	env := os.Environ()
-	for _, key := range s.EnvVars {
+	var env []string
+	switch {
+	case s.Sandbox.Enabled:
+		for _, key := range append(append([]string{}, sandbox.BaseEnv...), s.Sandbox.AllowEnv...) {
+			if val, ok := os.LookupEnv(key); ok {
+				env = append(env, key+"="+val)
+			}
+		}
+	case s.InheritEnv == nil || *s.InheritEnv:
+		env = os.Environ()
+	}
+	for _, entry := range s.EnvVars {
+		key, tmpl, literal := strings.Cut(entry, "=")
 		val := os.Getenv(key)
+		if literal {
+			val = envRefRe.ReplaceAllStringFunc(tmpl, func(ref string) string {
+				return os.Getenv(ref[2 : len(ref)-1])
+			})
+		}
 		env = append(env, fmt.Sprintf("%s=%s", key, val))
 	}
+	for _, key := range s.secretNames() {
+		env = append(env, key+"="+s.Secrets[key])
+	}
 	return env
 }
+
+// envRefRe matches the ${NAME} references expanded in env values. Any other
+// $ is literal, so values such as pa$word are passed unchanged.
+var envRefRe = regexp.MustCompile(`\$\{[A-Za-z_][A-Za-z0-9_]*\}`)
+
+func (s Scanner) secretNames() []string {
+	names := make([]string, 0, len(s.Secrets))
+	for key := range s.Secrets {
+		names = append(names, key)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lockedBuffer is a bytes.Buffer safe for the concurrent writes of stdout and stderr.
+type lockedBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *lockedBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *lockedBuffer) Bytes() []byte {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Bytes()
+}
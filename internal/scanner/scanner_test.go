@@ -2,10 +2,21 @@ package scanner
 
 import (
 	"context"
+	"os"
+	"path/filepath"
+	"runtime"
 	"strings"
 	"testing"
+
+	"github.com/cybrota/eskimo/internal/config"
+	"github.com/cybrota/eskimo/internal/sandbox"
 )
 
+func TestMain(m *testing.M) {
+	sandbox.HandleChild()
+	os.Exit(m.Run())
+}
+
 func TestRun(t *testing.T) {
 	t.Setenv("TESTVAR", "ok")
 	sc := Scanner{
@@ -33,3 +44,169 @@ func TestRunPreCommand(t *testing.T) {
 		t.Fatalf("expected pre-command output to be 'pre', got %q", strings.TrimSpace(string(out)))
 	}
 }
+
+func TestRunHook(t *testing.T) {
+	dir := t.TempDir()
+	sc := Scanner{EnvVars: []string{"STAGE=before"}}
+	out, err := sc.RunHook(context.Background(), config.Hook{Command: []string{"sh", "-c", "echo $STAGE; pwd"}}, dir)
+	if err != nil {
+		t.Fatalf("run hook failed: %v", err)
+	}
+	if !strings.Contains(string(out), "before") || !strings.Contains(string(out), dir) {
+		t.Fatalf("hook did not run in %s with scanner env: %q", dir, out)
+	}
+	if _, err := sc.RunHook(context.Background(), config.Hook{Command: []string{"false"}}, dir); err == nil {
+		t.Fatalf("expected failing hook to return an error")
+	}
+	if out, err := sc.RunHook(context.Background(), config.Hook{}, dir); err != nil || out != nil {
+		t.Fatalf("empty hook should be a no-op, got %q, %v", out, err)
+	}
+}
+
+func TestRunHookIsolation(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	t.Setenv("GITHUB_TOKEN", "leak")
+	sc := Scanner{Sandbox: config.Sandbox{Enabled: true, CPUSeconds: 7}}
+	out, err := sc.RunHook(context.Background(), config.Hook{Command: []string{"sh", "-c", "echo token=$GITHUB_TOKEN; ulimit -t"}}, t.TempDir())
+	if err != nil {
+		t.Fatalf("run hook failed: %v", err)
+	}
+	if got := strings.Fields(string(out)); len(got) != 2 || got[0] != "token=" || got[1] != "7" {
+		t.Fatalf("hook not run in the scanner sandbox: %q", out)
+	}
+
+	// containerized scanners run their hooks in the image, with the
+	// checkout writable
+	sc = Scanner{Image: "node:22", EnvVars: []string{"NPM_TOKEN"}}
+	args, err := sc.containerRun("/repos/app", false, nil, []string{"npm", "ci"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(args, " ")
+	for _, want := range []string{"docker run --rm --security-opt no-new-privileges", "-v /repos/app:/src -w /src", "-e NPM_TOKEN node:22 npm ci"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestScanSeparatesStdout(t *testing.T) {
+	sc := Scanner{
+		Command: []string{"sh", "-c", "echo out; echo err 1>&2"},
+	}
+	res, err := sc.Scan(context.Background(), config.TemplateVars{Path: "."})
+	if err != nil {
+		t.Fatalf("scan failed: %v", err)
+	}
+	if strings.TrimSpace(string(res.Stdout)) != "out" {
+		t.Fatalf("unexpected stdout %q", res.Stdout)
+	}
+	if !strings.Contains(string(res.Output), "err") || !strings.Contains(string(res.Output), "out") {
+		t.Fatalf("combined output missing streams: %q", res.Output)
+	}
+}
+
+func TestScanCollectsArtifacts(t *testing.T) {
+	repo := t.TempDir()
+	outDir := filepath.Join(t.TempDir(), "app", "semgrep")
+	if err := os.Symlink("/etc/hostname", filepath.Join(repo, "link.json")); err != nil {
+		t.Fatal(err)
+	}
+	sc := Scanner{
+		Name:    "semgrep",
+		Command: []string{"sh", "-c", "echo '{}' > {{.OutputDir}}/semgrep.json; mkdir -p reports; echo '{}' > reports/extra.json; test \"$ESKIMO_OUTPUT_DIR\" = " + outDir},
+		Outputs: []string{"reports/*.json", "*.json"},
+	}
+	res, err := sc.Scan(context.Background(), config.TemplateVars{Path: repo, OutputDir: outDir})
+	if err != nil {
+		t.Fatalf("scan failed: %v: %s", err, res.Output)
+	}
+	if got := strings.Join(res.Artifacts, " "); got != "reports/extra.json semgrep.json" {
+		t.Fatalf("unexpected artifacts %q", got)
+	}
+}
+
+func TestExpandArgs(t *testing.T) {
+	vars := config.TemplateVars{Repo: "app", Org: "acme", SHA: "abc123", OutputDir: "/out", Language: "Go"}
+	got, err := expandArgs([]string{"scan", "--project", "{{.Org}}/{{.Repo}}", "-o", "{{.OutputDir}}/{{.SHA}}.json", "{{.Language}}"}, vars)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(got, " ") != "scan --project acme/app -o /out/abc123.json Go" {
+		t.Fatalf("unexpected argv %q", got)
+	}
+}
+
+func TestContainerArgs(t *testing.T) {
+	sc := Scanner{
+		Name:    "trivy",
+		Command: []string{"trivy", "fs", "-o", "{{.OutputDir}}/trivy.sarif", "{{.Path}}"},
+		EnvVars: []string{"TRIVY_TOKEN", "MODE=ci"},
+		Image:   "aquasec/trivy:0.63.0",
+		Runtime: "podman",
+	}
+	args, err := sc.containerArgs(config.TemplateVars{Path: "/repos/app", OutputDir: "/tmp/out"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(args, " ")
+	for _, want := range []string{"podman run --rm", "-v /repos/app:/src:ro", "-v /tmp/out:/out", "-w /src", "-e TRIVY_TOKEN -e MODE aquasec/trivy:0.63.0 trivy fs -o /out/trivy.sarif /src"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("expected %q in %q", want, got)
+		}
+	}
+}
+
+func TestRunSandboxedEnv(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("sandbox is Linux only")
+	}
+	t.Setenv("GITHUB_TOKEN", "leak")
+	t.Setenv("SCANNER_TOKEN", "ok")
+	sc := Scanner{
+		Command: []string{"sh", "-c", "echo token=$GITHUB_TOKEN scanner=$SCANNER_TOKEN"},
+		EnvVars: []string{"SCANNER_TOKEN"},
+		Sandbox: config.Sandbox{Enabled: true, CPUSeconds: 60},
+	}
+	out, err := sc.Run(context.Background(), ".")
+	if err != nil {
+		t.Fatalf("run failed: %v: %s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "token= scanner=ok" {
+		t.Fatalf("unexpected environment in sandbox: %q", got)
+	}
+}
+
+func TestRunIsolatedEnv(t *testing.T) {
+	t.Setenv("GITHUB_TOKEN", "leak")
+	t.Setenv("WIZ_SECRET", "s3cret")
+	inherit := false
+	sc := Scanner{
+		Command:    []string{"/bin/sh", "-c", "echo token=$GITHUB_TOKEN wiz=$WIZ mode=$MODE"},
+		EnvVars:    []string{"WIZ=${WIZ_SECRET}", "MODE=ci"},
+		InheritEnv: &inherit,
+	}
+	out, err := sc.Run(context.Background(), ".")
+	if err != nil {
+		t.Fatalf("run failed: %v: %s", err, out)
+	}
+	if got := strings.TrimSpace(string(out)); got != "token= wiz=s3cret mode=ci" {
+		t.Fatalf("unexpected environment: %q", got)
+	}
+}
+
+func TestBuildEnvExpandsOnlyBracedReferences(t *testing.T) {
+	t.Setenv("WIZ_SECRET", "s3cret")
+	t.Setenv("word", "oops")
+	inherit := false
+	sc := Scanner{
+		EnvVars:    []string{"PASSWORD=pa$word", "TOKEN=${WIZ_SECRET}-$WIZ_SECRET", "PRICE=$5", "EMPTY=${UNSET_VAR}"},
+		InheritEnv: &inherit,
+	}
+	got := strings.Join(sc.buildEnv(), " ")
+	if want := "PASSWORD=pa$word TOKEN=s3cret-$WIZ_SECRET PRICE=$5 EMPTY="; got != want {
+		t.Fatalf("env %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,131 @@
+package secrets
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// minRedactLen avoids redacting short values such as "1" or "ci" everywhere.
+const minRedactLen = 4
+
+// Resolve resolves a secret reference. ok is false when ref is not a
+// reference and should be used as a plain value. Supported references:
+//
+//	file:/run/secrets/token         file content, trailing newline trimmed
+//	env:NAME                        value of an environment variable
+//	awssm:/path/secret.json#KEY     key of a Secrets Manager secret stored as a
+//	                                JSON file (SecretString or the raw object)
+//
+// file: and env: references also accept #KEY to select a key of a JSON object.
+func Resolve(ref string) (value string, ok bool, err error) {
+	scheme, rest, found := strings.Cut(ref, ":")
+	if !found {
+		return "", false, nil
+	}
+	src, key, hasKey := strings.Cut(rest, "#")
+	var raw string
+	switch scheme {
+	case "file", "awssm":
+		data, err := os.ReadFile(src)
+		if err != nil {
+			return "", true, fmt.Errorf("read secret %s: %w", src, err)
+		}
+		raw = strings.TrimRight(string(data), "\r\n")
+	case "env":
+		v, set := os.LookupEnv(src)
+		if !set {
+			return "", true, fmt.Errorf("secret environment variable %s is not set", src)
+		}
+		raw = v
+	default:
+		return "", false, nil
+	}
+	if scheme == "awssm" {
+		if !hasKey {
+			return "", true, fmt.Errorf("secret %s: awssm references require #KEY", src)
+		}
+		raw = unwrapSecretString(raw)
+	}
+	if !hasKey {
+		return raw, true, nil
+	}
+	var obj map[string]any
+	if err := json.Unmarshal([]byte(raw), &obj); err != nil {
+		return "", true, fmt.Errorf("secret %s is not a JSON object: %w", src, err)
+	}
+	v, found := obj[key]
+	if !found {
+		return "", true, fmt.Errorf("secret %s has no key %s", src, key)
+	}
+	if s, isString := v.(string); isString {
+		return s, true, nil
+	}
+	return fmt.Sprint(v), true, nil
+}
+
+// unwrapSecretString returns the SecretString of a GetSecretValue response,
+// or data unchanged when it is already the secret object.
+func unwrapSecretString(data string) string {
+	var resp struct {
+		SecretString *string `json:"SecretString"`
+	}
+	if err := json.Unmarshal([]byte(data), &resp); err == nil && resp.SecretString != nil {
+		return *resp.SecretString
+	}
+	return data
+}
+
+// Redactor masks known secret values in text. The zero value and a nil
+// Redactor redact nothing.
+type Redactor struct {
+	mu       sync.RWMutex
+	values   map[string]bool
+	replacer *strings.Replacer
+}
+
+func NewRedactor(values ...string) *Redactor {
+	r := &Redactor{}
+	r.Add(values...)
+	return r
+}
+
+// Add registers additional secret values.
+func (r *Redactor) Add(values ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.values == nil {
+		r.values = make(map[string]bool)
+	}
+	for _, v := range values {
+		if len(v) >= minRedactLen {
+			r.values[v] = true
+		}
+	}
+	// replace longer values first so a secret containing another is fully masked
+	sorted := make([]string, 0, len(r.values))
+	for v := range r.values {
+		sorted = append(sorted, v)
+	}
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	pairs := make([]string, 0, 2*len(sorted))
+	for _, v := range sorted {
+		pairs = append(pairs, v, "***")
+	}
+	r.replacer = strings.NewReplacer(pairs...)
+}
+
+func (r *Redactor) Redact(s string) string {
+	if r == nil {
+		return s
+	}
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if r.replacer == nil {
+		return s
+	}
+	return r.replacer.Replace(s)
+}
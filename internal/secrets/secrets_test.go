@@ -0,0 +1,58 @@
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	dir := t.TempDir()
+	tokenFile := filepath.Join(dir, "token")
+	os.WriteFile(tokenFile, []byte("file-secret\n"), 0600)
+	smFile := filepath.Join(dir, "eskimo-config.json")
+	os.WriteFile(smFile, []byte(`{"SecretString":"{\"WIZ_CLIENT_ID\":\"wiz-id\"}"}`), 0600)
+	rawFile := filepath.Join(dir, "raw.json")
+	os.WriteFile(rawFile, []byte(`{"WIZ_CLIENT_ID":"raw-id"}`), 0600)
+	t.Setenv("SOME_SECRET", "env-secret")
+
+	cases := map[string]string{
+		"file:" + tokenFile:                   "file-secret",
+		"env:SOME_SECRET":                     "env-secret",
+		"awssm:" + smFile + "#WIZ_CLIENT_ID":  "wiz-id",
+		"awssm:" + rawFile + "#WIZ_CLIENT_ID": "raw-id",
+		"file:" + rawFile + "#WIZ_CLIENT_ID":  "raw-id",
+	}
+	for ref, want := range cases {
+		got, ok, err := Resolve(ref)
+		if err != nil || !ok || got != want {
+			t.Errorf("%s: got %q, %v, %v", ref, got, ok, err)
+		}
+	}
+
+	if _, ok, _ := Resolve("plain-value"); ok {
+		t.Errorf("plain value treated as reference")
+	}
+	if _, ok, _ := Resolve("https://example.com"); ok {
+		t.Errorf("URL treated as reference")
+	}
+	if _, _, err := Resolve("env:MISSING_SECRET_VAR"); err == nil {
+		t.Errorf("expected error for unset variable")
+	}
+	if _, _, err := Resolve("awssm:" + smFile + "#NOPE"); err == nil {
+		t.Errorf("expected error for missing key")
+	}
+}
+
+func TestRedactor(t *testing.T) {
+	r := NewRedactor("supersecret", "ab")
+	r.Add("token-123")
+	got := r.Redact("auth supersecret with token-123 ab")
+	if got != "auth *** with *** ab" {
+		t.Fatalf("unexpected redaction: %q", got)
+	}
+	var nilRedactor *Redactor
+	if nilRedactor.Redact("x") != "x" {
+		t.Fatalf("nil redactor must be a no-op")
+	}
+}
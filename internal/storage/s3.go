@@ -0,0 +1,275 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// S3Options configures an S3Client. Endpoint selects an S3-compatible
+// service such as MinIO; objects are then addressed path-style
+// (endpoint/bucket/key). Without it the AWS regional endpoint is used with
+// virtual-hosted addressing.
+type S3Options struct {
+	Bucket   string
+	Region   string
+	Endpoint string
+}
+
+// S3Client uploads objects to an S3-compatible bucket. Requests are signed
+// with AWS Signature Version 4 using credentials from the environment
+// (AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY, AWS_SESSION_TOKEN) or, on ECS
+// and Fargate, from the container credentials endpoint.
+type S3Client struct {
+	bucket    string
+	region    string
+	base      *url.URL
+	pathStyle bool
+	http      *http.Client
+	creds     *credentialSource
+	now       func() time.Time
+}
+
+func NewS3Client(opts S3Options) (*S3Client, error) {
+	if opts.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	region := opts.Region
+	if region == "" {
+		region = os.Getenv("AWS_REGION")
+	}
+	if region == "" {
+		region = os.Getenv("AWS_DEFAULT_REGION")
+	}
+	if region == "" {
+		region = "us-east-1"
+	}
+	c := &S3Client{
+		bucket: opts.Bucket,
+		region: region,
+		http:   &http.Client{Timeout: 5 * time.Minute},
+		creds:  &credentialSource{},
+		now:    time.Now,
+	}
+	endpoint := opts.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.s3.%s.amazonaws.com", opts.Bucket, region)
+	} else {
+		c.pathStyle = true
+	}
+	base, err := url.Parse(strings.TrimRight(endpoint, "/"))
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", endpoint)
+	}
+	c.base = base
+	return c, nil
+}
+
+// Bucket returns the bucket objects are uploaded to.
+func (c *S3Client) Bucket() string {
+	return c.bucket
+}
+
+// PutObject uploads body under key.
+func (c *S3Client) PutObject(ctx context.Context, key string, body []byte, contentType string) error {
+	creds, err := c.creds.get(ctx, c.http)
+	if err != nil {
+		return err
+	}
+	u := *c.base
+	u.Path = "/" + key
+	if c.pathStyle {
+		u.Path = "/" + c.bucket + "/" + key
+	}
+	u.RawPath = uriEncode(u.Path, false)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, u.String(), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	sum := sha256.Sum256(body)
+	signRequest(req, hex.EncodeToString(sum[:]), creds, c.region, c.now())
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("put s3://%s/%s: %w", c.bucket, key, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("put s3://%s/%s: %s: %s", c.bucket, key, resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// Credentials are AWS access keys.
+type Credentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// ecsCredentialsHost serves AWS_CONTAINER_CREDENTIALS_RELATIVE_URI.
+const ecsCredentialsHost = "http://169.254.170.2"
+
+// credentialSource loads credentials once and refreshes temporary ones
+// shortly before they expire.
+type credentialSource struct {
+	mu     sync.Mutex
+	cached *Credentials
+}
+
+func (s *credentialSource) get(ctx context.Context, client *http.Client) (Credentials, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached != nil && (s.cached.Expires.IsZero() || time.Until(s.cached.Expires) > 5*time.Minute) {
+		return *s.cached, nil
+	}
+	creds, err := loadCredentials(ctx, client)
+	if err != nil {
+		return Credentials{}, err
+	}
+	s.cached = &creds
+	return creds, nil
+}
+
+func loadCredentials(ctx context.Context, client *http.Client) (Credentials, error) {
+	if id, secret := os.Getenv("AWS_ACCESS_KEY_ID"), os.Getenv("AWS_SECRET_ACCESS_KEY"); id != "" && secret != "" {
+		return Credentials{AccessKeyID: id, SecretAccessKey: secret, SessionToken: os.Getenv("AWS_SESSION_TOKEN")}, nil
+	}
+	endpoint := os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+	if rel := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); rel != "" {
+		endpoint = ecsCredentialsHost + rel
+	}
+	if endpoint == "" {
+		return Credentials{}, fmt.Errorf("no AWS credentials: set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY or run with an ECS task role")
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return Credentials{}, err
+	}
+	if token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN"); token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Credentials{}, fmt.Errorf("fetch container credentials: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Credentials{}, fmt.Errorf("fetch container credentials: %s", resp.Status)
+	}
+	var body struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		return Credentials{}, fmt.Errorf("decode container credentials: %w", err)
+	}
+	return Credentials{AccessKeyID: body.AccessKeyID, SecretAccessKey: body.SecretAccessKey, SessionToken: body.Token, Expires: body.Expiration}, nil
+}
+
+// signRequest adds SigV4 headers for the s3 service. Host and every header
+// already set on req are signed.
+func signRequest(req *http.Request, payloadHash string, creds Credentials, region string, now time.Time) {
+	now = now.UTC()
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonical := strings.Join([]string{
+		req.Method,
+		uriEncode(req.URL.Path, false),
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+	scope := date + "/" + region + "/s3/aws4_request"
+	sum := sha256.Sum256([]byte(canonical))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(sum[:])
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	for _, part := range []string{region, "s3", "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		creds.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
+
+func canonicalQuery(q url.Values) string {
+	keys := make([]string, 0, len(q))
+	for k := range q {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	var parts []string
+	for _, k := range keys {
+		vals := append([]string{}, q[k]...)
+		sort.Strings(vals)
+		for _, v := range vals {
+			parts = append(parts, uriEncode(k, true)+"="+uriEncode(v, true))
+		}
+	}
+	return strings.Join(parts, "&")
+}
+
+// uriEncode percent-encodes everything but the RFC 3986 unreserved
+// characters, as SigV4 requires. Slashes are kept unless encodeSlash is set.
+func uriEncode(s string, encodeSlash bool) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'A' <= c && c <= 'Z', 'a' <= c && c <= 'z', '0' <= c && c <= '9', c == '-', c == '_', c == '.', c == '~':
+			b.WriteByte(c)
+		case c == '/' && !encodeSlash:
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+	return b.String()
+}
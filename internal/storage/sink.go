@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// S3Sink uploads the run report, the findings of the run as one SARIF
+// document and the kept scanner artifacts. Objects are stored under
+// <org>/<run-id>/, artifacts under <org>/<run-id>/<repo>/<scanner>/.
+type S3Sink struct {
+	client *S3Client
+}
+
+func NewS3Sink(client *S3Client) *S3Sink {
+	return &S3Sink{client: client}
+}
+
+func (s *S3Sink) Name() string {
+	return "s3"
+}
+
+func (s *S3Sink) Publish(ctx context.Context, rep *report.Report) error {
+	base := path.Join(rep.Org, rep.RunID)
+	var errs []error
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode report: %w", err)
+	}
+	if err := s.client.PutObject(ctx, path.Join(base, "report.json"), data, "application/json"); err != nil {
+		errs = append(errs, err)
+	}
+	sarif, err := findings.EncodeSARIF(rep.Findings())
+	if err != nil {
+		return fmt.Errorf("encode SARIF: %w", err)
+	}
+	if err := s.client.PutObject(ctx, path.Join(base, "findings.sarif"), sarif, "application/sarif+json"); err != nil {
+		errs = append(errs, err)
+	}
+	for _, repo := range rep.Repos {
+		for _, scan := range repo.Scans {
+			for _, a := range scan.Artifacts {
+				if err := s.uploadArtifact(ctx, rep.ArtifactsDir, base, a); err != nil {
+					errs = append(errs, err)
+				}
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (s *S3Sink) uploadArtifact(ctx context.Context, dir, base, artifact string) error {
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(artifact)))
+	if err != nil {
+		return fmt.Errorf("read artifact: %w", err)
+	}
+	contentType := mime.TypeByExtension(path.Ext(artifact))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	return s.client.PutObject(ctx, path.Join(base, artifact), data, contentType)
+}
@@ -0,0 +1,300 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+// Finding statuses.
+const (
+	StatusOpen       = "open"
+	StatusFixed      = "fixed"
+	StatusSuppressed = "suppressed"
+)
+
+// Record tracks one finding, identified by its fingerprint, across runs.
+// OpenedAt is when the finding was first reported, or reported again after
+// it had been fixed.
+type Record struct {
+	findings.Finding
+	Status   string     `json:"status"`
+	OpenedAt time.Time  `json:"opened_at"`
+	LastSeen time.Time  `json:"last_seen"`
+	FixedAt  *time.Time `json:"fixed_at,omitempty"`
+	LastRun  string     `json:"last_run"`
+}
+
+// Store is a findings database kept as JSON files in a directory with one
+// subdirectory per organization: one report per run under <org>/runs/ and an
+// index of every finding ever reported in <org>/findings.json. It is a
+// report.Sink so each run is recorded as it is published. MaxRuns bounds the
+// run reports kept per organization, oldest first; the finding index is
+// never pruned. Zero keeps every run.
+type Store struct {
+	MaxRuns int
+
+	dir string
+	mu  sync.Mutex
+}
+
+const indexFile = "findings.json"
+
+// Open opens the store in dir, creating the directory if needed.
+func Open(dir string) (*Store, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("create store: %w", err)
+	}
+	return &Store{dir: dir}, nil
+}
+
+func (s *Store) Name() string {
+	return "store"
+}
+
+// Publish saves the default branch part of rep; scans of other commits, such
+// as pull request heads, are not recorded.
+func (s *Store) Publish(ctx context.Context, rep *report.Report) error {
+	if def := rep.DefaultBranch(); def != rep {
+		if len(def.Repos) == 0 {
+			return nil
+		}
+		rep = def
+	}
+	return s.Save(rep)
+}
+
+// Save records the run report and updates the finding index. Findings of a
+// repository and scanner that are no longer reported are marked fixed,
+// unless the scanner failed for that repository. Suppressed findings keep
+// their record with StatusSuppressed rather than being marked fixed.
+func (s *Store) Save(rep *report.Report) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if rep.RunID == "" || rep.Org == "" {
+		return fmt.Errorf("report has no run ID or organization")
+	}
+	runs := filepath.Join(s.dir, rep.Org, "runs")
+	if err := os.MkdirAll(runs, 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(rep, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := writeFile(filepath.Join(runs, rep.RunID+".json"), data); err != nil {
+		return err
+	}
+	if err := s.prune(runs); err != nil {
+		return err
+	}
+	index, err := s.load(rep.Org)
+	if err != nil {
+		return err
+	}
+	now := rep.FinishedAt
+	if now.IsZero() {
+		now = time.Now().UTC()
+	}
+	for _, repo := range rep.Repos {
+		if repo.Status != report.StatusScanned {
+			continue
+		}
+		for _, scan := range repo.Scans {
+			if scan.Error != "" {
+				continue
+			}
+			seen := make(map[string]bool, len(scan.Findings)+len(scan.Suppressed)+len(scan.Duplicates))
+			observe := func(f findings.Finding, status string) {
+				seen[f.Fingerprint] = true
+				rec, ok := index[f.Fingerprint]
+				if !ok || rec.Status == StatusFixed {
+					rec = &Record{OpenedAt: now}
+					index[f.Fingerprint] = rec
+				}
+				rec.Finding = f
+				rec.Status = status
+				rec.LastSeen = now
+				rec.LastRun = rep.RunID
+			}
+			for _, f := range scan.Findings {
+				observe(f, StatusOpen)
+			}
+			for _, sf := range scan.Suppressed {
+				observe(sf.Finding, StatusSuppressed)
+			}
+			// merged into another scanner's finding, which carries the status
+			for _, f := range scan.Duplicates {
+				seen[f.Fingerprint] = true
+				if rec, ok := index[f.Fingerprint]; ok {
+					rec.LastSeen = now
+				}
+			}
+			for fp, rec := range index {
+				if rec.Repo == repo.Name && rec.Scanner == scan.Scanner && rec.Status != StatusFixed && !seen[fp] {
+					fixed := now
+					rec.Status = StatusFixed
+					rec.FixedAt = &fixed
+					rec.LastRun = rep.RunID
+				}
+			}
+		}
+	}
+	data, err = json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(filepath.Join(s.dir, rep.Org, indexFile), data)
+}
+
+// Query selects findings of an organization. Other zero fields match
+// everything.
+type Query struct {
+	Org         string
+	Repo        string
+	Scanner     string
+	MinSeverity findings.Severity
+	Status      string
+	// Since keeps findings opened at or after this time.
+	Since time.Time
+	// MinAge keeps findings that have been open for at least this long.
+	MinAge time.Duration
+}
+
+// Query returns the matching records, most severe first.
+func (s *Store) Query(q Query, now time.Time) ([]Record, error) {
+	s.mu.Lock()
+	index, err := s.load(q.Org)
+	s.mu.Unlock()
+	if err != nil {
+		return nil, err
+	}
+	var out []Record
+	for _, rec := range index {
+		switch {
+		case q.Repo != "" && rec.Repo != q.Repo,
+			q.Scanner != "" && rec.Scanner != q.Scanner,
+			rec.Severity < q.MinSeverity,
+			q.Status != "" && rec.Status != q.Status,
+			!q.Since.IsZero() && rec.OpenedAt.Before(q.Since),
+			q.MinAge > 0 && (rec.Status != StatusOpen || now.Sub(rec.OpenedAt) < q.MinAge):
+			continue
+		}
+		out = append(out, *rec)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Scanner != b.Scanner {
+			return a.Scanner < b.Scanner
+		}
+		if a.RuleID != b.RuleID {
+			return a.RuleID < b.RuleID
+		}
+		return a.Fingerprint < b.Fingerprint
+	})
+	return out, nil
+}
+
+// PreviousRun returns the latest stored run of the organization that started
+// before rep, or nil when there is none. Run IDs sort chronologically.
+func (s *Store) PreviousRun(rep *report.Report) (*report.Report, error) {
+	entries, err := os.ReadDir(filepath.Join(s.dir, rep.Org, "runs"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var prev string
+	for _, e := range entries {
+		id, ok := strings.CutSuffix(e.Name(), ".json")
+		if ok && id < rep.RunID && id > prev {
+			prev = id
+		}
+	}
+	if prev == "" {
+		return nil, nil
+	}
+	data, err := os.ReadFile(filepath.Join(s.dir, rep.Org, "runs", prev+".json"))
+	if err != nil {
+		return nil, err
+	}
+	var out report.Report
+	if err := json.Unmarshal(data, &out); err != nil {
+		return nil, fmt.Errorf("decode run %s: %w", prev, err)
+	}
+	return &out, nil
+}
+
+// prune removes the oldest run reports in runs beyond MaxRuns. Run IDs sort
+// chronologically.
+func (s *Store) prune(runs string) error {
+	if s.MaxRuns <= 0 {
+		return nil
+	}
+	entries, err := os.ReadDir(runs)
+	if err != nil {
+		return err
+	}
+	var ids []string
+	for _, e := range entries {
+		if id, ok := strings.CutSuffix(e.Name(), ".json"); ok {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	for len(ids) > s.MaxRuns {
+		if err := os.Remove(filepath.Join(runs, ids[0]+".json")); err != nil {
+			return err
+		}
+		ids = ids[1:]
+	}
+	return nil
+}
+
+func (s *Store) load(org string) (map[string]*Record, error) {
+	index := make(map[string]*Record)
+	data, err := os.ReadFile(filepath.Join(s.dir, org, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("decode %s: %w", indexFile, err)
+	}
+	return index, nil
+}
+
+// writeFile replaces path atomically so a crash never leaves a torn file.
+func writeFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
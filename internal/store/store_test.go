@@ -0,0 +1,184 @@
+package store
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+	"github.com/cybrota/eskimo/internal/report"
+)
+
+func finding(repo, scanner, rule string, sev findings.Severity) findings.Finding {
+	f := findings.Finding{Repo: repo, Scanner: scanner, RuleID: rule, Severity: sev, Path: "go.mod"}
+	f.Fingerprint = findings.Fingerprint(f, "")
+	return f
+}
+
+func run(id string, at time.Time, scans map[string][]report.Scan) *report.Report {
+	rep := &report.Report{Org: "acme", RunID: id, FinishedAt: at}
+	for name, list := range scans {
+		rr := rep.Repo(name)
+		rr.Status = report.StatusScanned
+		rr.Scans = list
+	}
+	return rep
+}
+
+func TestStoreTracksStatus(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cve := finding("app", "trivy", "CVE-1", findings.SeverityCritical)
+	lint := finding("app", "semgrep", "R1", findings.SeverityLow)
+	libCVE := finding("lib", "trivy", "CVE-2", findings.SeverityHigh)
+	if err := s.Save(run("r1", day1, map[string][]report.Scan{
+		"app": {{Scanner: "trivy", Findings: []findings.Finding{cve}}, {Scanner: "semgrep", Findings: []findings.Finding{lint}}},
+		"lib": {{Scanner: "trivy", Findings: []findings.Finding{libCVE}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	day40 := day1.Add(40 * 24 * time.Hour)
+	// semgrep no longer reports R1; trivy failed on lib so CVE-2 stays open
+	if err := s.Save(run("r2", day40, map[string][]report.Scan{
+		"app": {{Scanner: "trivy", Findings: []findings.Finding{cve}}, {Scanner: "semgrep"}},
+		"lib": {{Scanner: "trivy", Error: "exit status 1"}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acme", "runs", "r2.json")); err != nil {
+		t.Fatalf("run report not stored: %v", err)
+	}
+
+	fixed, err := s.Query(Query{Org: "acme", Status: StatusFixed}, day40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(fixed) != 1 || fixed[0].RuleID != "R1" || fixed[0].FixedAt == nil {
+		t.Fatalf("unexpected fixed findings %+v", fixed)
+	}
+	old, err := s.Query(Query{Org: "acme", Scanner: "trivy", MinSeverity: findings.SeverityCritical, MinAge: 30 * 24 * time.Hour}, day40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(old) != 1 || old[0].Fingerprint != cve.Fingerprint || !old[0].OpenedAt.Equal(day1) {
+		t.Fatalf("unexpected long-open findings %+v", old)
+	}
+	open, err := s.Query(Query{Org: "acme", Status: StatusOpen}, day40)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 2 || open[0].RuleID != "CVE-1" || open[1].RuleID != "CVE-2" {
+		t.Fatalf("unexpected open findings %+v", open)
+	}
+
+	// a fixed finding that comes back is reopened
+	day50 := day40.Add(10 * 24 * time.Hour)
+	if err := s.Save(run("r3", day50, map[string][]report.Scan{
+		"app": {{Scanner: "semgrep", Findings: []findings.Finding{lint}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := s.Query(Query{Org: "acme", Repo: "app", Scanner: "semgrep", Since: day50}, day50)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened) != 1 || reopened[0].Status != StatusOpen || reopened[0].FixedAt != nil {
+		t.Fatalf("expected R1 reopened, got %+v", reopened)
+	}
+
+	// a suppressed finding is neither open nor fixed and keeps its history
+	day60 := day50.Add(10 * 24 * time.Hour)
+	if err := s.Save(run("r4", day60, map[string][]report.Scan{
+		"app": {{Scanner: "trivy", Suppressed: []report.Suppressed{{Finding: cve, Reason: "not reachable"}}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	suppressed, err := s.Query(Query{Org: "acme", Status: StatusSuppressed}, day60)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(suppressed) != 1 || suppressed[0].Fingerprint != cve.Fingerprint || !suppressed[0].OpenedAt.Equal(day1) || suppressed[0].FixedAt != nil {
+		t.Fatalf("unexpected suppressed findings %+v", suppressed)
+	}
+}
+
+func TestPreviousRun(t *testing.T) {
+	s, err := Open(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := run("20250101T000000.000Z", time.Now(), nil)
+	if prev, err := s.PreviousRun(first); err != nil || prev != nil {
+		t.Fatalf("expected no previous run, got %+v, %v", prev, err)
+	}
+	for _, id := range []string{"20250101T000000.000Z", "20250102T000000.000Z", "20250103T000000.000Z"} {
+		if err := s.Save(run(id, time.Now(), nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	prev, err := s.PreviousRun(&report.Report{Org: "acme", RunID: "20250103T000000.000Z"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prev == nil || prev.RunID != "20250102T000000.000Z" {
+		t.Fatalf("unexpected previous run %+v", prev)
+	}
+}
+
+func TestPublishIgnoresOtherBranches(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	day1 := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	cve := finding("app", "trivy", "CVE-1", findings.SeverityCritical)
+	if err := s.Publish(context.Background(), run("r1", day1, map[string][]report.Scan{
+		"app": {{Scanner: "trivy", Findings: []findings.Finding{cve}}},
+	})); err != nil {
+		t.Fatal(err)
+	}
+	// the pull request fixes CVE-1 but is not merged yet
+	pr := run("r2", day1.Add(time.Hour), map[string][]report.Scan{"app": {{Scanner: "trivy"}}})
+	pr.Repos[0].Ref = "refs/pull/7/head"
+	if err := s.Publish(context.Background(), pr); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "acme", "runs", "r2.json")); !os.IsNotExist(err) {
+		t.Fatalf("pull request run stored: %v", err)
+	}
+	open, err := s.Query(Query{Org: "acme", Status: StatusOpen}, day1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(open) != 1 || open[0].Fingerprint != cve.Fingerprint {
+		t.Fatalf("expected CVE-1 to stay open, got %+v", open)
+	}
+}
+
+func TestStoreMaxRuns(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.MaxRuns = 2
+	for _, id := range []string{"20250101T000000.000Z", "20250102T000000.000Z", "20250103T000000.000Z"} {
+		if err := s.Save(run(id, time.Now(), nil)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := os.ReadDir(filepath.Join(dir, "acme", "runs"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || entries[0].Name() != "20250102T000000.000Z.json" {
+		t.Fatalf("expected the two latest runs to be kept, got %v", entries)
+	}
+}
@@ -0,0 +1,148 @@
+package suppress
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+// FileName is the suppression file looked up in the root of every cloned
+// repository.
+const FileName = ".eskimo-ignore.yaml"
+
+// Suppression hides matching findings until it expires. Every selector that
+// is set must match: Fingerprint, Rule (rule ID), Path (a glob on the
+// finding path; a trailing /** matches everything below a directory) and
+// Repo (a glob on the repository name). Reason, Owner and Expires
+// (YYYY-MM-DD, inclusive) are mandatory.
+type Suppression struct {
+	Fingerprint string `yaml:"fingerprint"`
+	Rule        string `yaml:"rule"`
+	Path        string `yaml:"path"`
+	Repo        string `yaml:"repo"`
+	Reason      string `yaml:"reason"`
+	Owner       string `yaml:"owner"`
+	Expires     string `yaml:"expires"`
+
+	expiry time.Time
+	source string
+}
+
+// List is a set of suppressions.
+type List []Suppression
+
+type file struct {
+	Suppressions []Suppression `yaml:"suppressions"`
+}
+
+// Load reads a suppression file. A missing file yields an empty list when
+// optional is set.
+func Load(name string, optional bool) (List, error) {
+	data, err := os.ReadFile(name)
+	if optional && os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(name, data)
+}
+
+// Parse decodes and validates suppressions; source names them in errors.
+func Parse(source string, data []byte) (List, error) {
+	var f file
+	if err := yaml.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	for i := range f.Suppressions {
+		s := &f.Suppressions[i]
+		s.source = source
+		if err := s.validate(); err != nil {
+			return nil, fmt.Errorf("%s: suppression %d: %w", source, i+1, err)
+		}
+	}
+	return f.Suppressions, nil
+}
+
+func (s *Suppression) validate() error {
+	if s.Fingerprint == "" && s.Rule == "" && s.Path == "" && s.Repo == "" {
+		return fmt.Errorf("needs at least one of fingerprint, rule, path or repo")
+	}
+	for _, glob := range []string{s.Path, s.Repo} {
+		if _, err := path.Match(strings.TrimSuffix(glob, "/**"), ""); err != nil {
+			return fmt.Errorf("invalid glob %q", glob)
+		}
+	}
+	if strings.TrimSpace(s.Reason) == "" || strings.TrimSpace(s.Owner) == "" {
+		return fmt.Errorf("reason and owner are required")
+	}
+	day, err := time.Parse(time.DateOnly, s.Expires)
+	if err != nil {
+		return fmt.Errorf("expires must be a date (YYYY-MM-DD): %q", s.Expires)
+	}
+	s.expiry = day.AddDate(0, 0, 1)
+	return nil
+}
+
+// Source returns the file the suppression was read from.
+func (s Suppression) Source() string {
+	return s.source
+}
+
+// Expired reports whether the suppression no longer applies at now.
+func (s Suppression) Expired(now time.Time) bool {
+	return !now.Before(s.expiry)
+}
+
+func (s Suppression) matches(f findings.Finding) bool {
+	return (s.Fingerprint == "" || s.Fingerprint == f.Fingerprint) &&
+		(s.Rule == "" || s.Rule == f.RuleID) &&
+		(s.Path == "" || matchPath(s.Path, f.Path)) &&
+		(s.Repo == "" || matchPath(s.Repo, f.Repo))
+}
+
+func matchPath(pattern, name string) bool {
+	if dir, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return name == dir || strings.HasPrefix(name, dir+"/")
+	}
+	ok, _ := path.Match(pattern, name)
+	return ok
+}
+
+// ForRepo restricts the list to repo, as for a file committed in the
+// repository itself. Repo selectors of the entries are replaced.
+func (l List) ForRepo(repo string) List {
+	out := make(List, len(l))
+	for i, s := range l {
+		s.Repo = repo
+		out[i] = s
+	}
+	return out
+}
+
+// Expired returns the suppressions that have expired at now.
+func (l List) Expired(now time.Time) List {
+	var out List
+	for _, s := range l {
+		if s.Expired(now) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// Match returns the first active suppression matching f.
+func (l List) Match(f findings.Finding, now time.Time) (Suppression, bool) {
+	for _, s := range l {
+		if !s.Expired(now) && s.matches(f) {
+			return s, true
+		}
+	}
+	return Suppression{}, false
+}
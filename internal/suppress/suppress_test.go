@@ -0,0 +1,83 @@
+package suppress
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cybrota/eskimo/internal/findings"
+)
+
+const central = `suppressions:
+  - rule: CVE-2023-0001
+    repo: "legacy-*"
+    reason: vulnerable code path is not reachable
+    owner: "@acme/platform"
+    expires: 2025-06-30
+  - path: testdata/**
+    reason: test fixtures
+    owner: security@acme.io
+    expires: 2099-01-01
+  - fingerprint: 0123456789abcdef
+    reason: accepted risk
+    owner: "@alice"
+    expires: 2024-01-01
+`
+
+func TestMatch(t *testing.T) {
+	list, err := Parse("central", []byte(central))
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Date(2025, 6, 30, 23, 0, 0, 0, time.UTC)
+	cases := []struct {
+		name string
+		f    findings.Finding
+		want bool
+	}{
+		{"rule and repo", findings.Finding{Repo: "legacy-api", RuleID: "CVE-2023-0001"}, true},
+		{"rule in other repo", findings.Finding{Repo: "app", RuleID: "CVE-2023-0001"}, false},
+		{"path below directory", findings.Finding{Repo: "app", Path: "testdata/keys/id_rsa"}, true},
+		{"similar directory", findings.Finding{Repo: "app", Path: "testdata2/key"}, false},
+		{"expired fingerprint", findings.Finding{Repo: "app", Fingerprint: "0123456789abcdef"}, false},
+	}
+	for _, c := range cases {
+		if _, got := list.Match(c.f, now); got != c.want {
+			t.Errorf("%s: match = %v, want %v", c.name, got, c.want)
+		}
+	}
+	// the rule expires at the end of its expiry day
+	if _, ok := list.Match(cases[0].f, now.Add(2*time.Hour)); ok {
+		t.Errorf("suppression still active after expiry")
+	}
+	if expired := list.Expired(now); len(expired) != 1 || expired[0].Fingerprint == "" {
+		t.Errorf("unexpected expired list %+v", expired)
+	}
+}
+
+func TestParseRequiresMetadata(t *testing.T) {
+	for name, data := range map[string]string{
+		"no reason":   "suppressions:\n  - rule: R1\n    owner: a\n    expires: 2099-01-01\n",
+		"no expiry":   "suppressions:\n  - rule: R1\n    owner: a\n    reason: b\n",
+		"bad date":    "suppressions:\n  - rule: R1\n    owner: a\n    reason: b\n    expires: soon\n",
+		"no selector": "suppressions:\n  - owner: a\n    reason: b\n    expires: 2099-01-01\n",
+	} {
+		if _, err := Parse(name, []byte(data)); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestForRepo(t *testing.T) {
+	list, err := Parse("repo", []byte("suppressions:\n  - rule: R1\n    repo: \"*\"\n    owner: a\n    reason: b\n    expires: 2099-01-01\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	scoped := list.ForRepo("app")
+	now := time.Now()
+	if _, ok := scoped.Match(findings.Finding{Repo: "other", RuleID: "R1"}, now); ok {
+		t.Errorf("per-repo suppression applied to another repository")
+	}
+	if _, ok := scoped.Match(findings.Finding{Repo: "app", RuleID: "R1"}, now); !ok {
+		t.Errorf("per-repo suppression not applied")
+	}
+}
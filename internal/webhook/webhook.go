@@ -0,0 +1,159 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v55/github"
+)
+
+// MaxPayload is the largest webhook body accepted; GitHub caps payloads at
+// 25 MB.
+const MaxPayload = 25 << 20
+
+// Event is a request to scan a repository at a given commit. Ref is the
+// pushed ref, or refs/pull/<number>/head for pull requests.
+type Event struct {
+	Repo string
+	SHA  string
+	Ref  string
+	Kind string
+}
+
+// ScanFunc scans the repository described by ev.
+type ScanFunc func(ctx context.Context, ev Event) error
+
+// Server receives GitHub webhooks, verifies their signature and queues scans
+// of the affected repositories. Scans are processed one at a time. Pull
+// requests from forks run code anyone can submit, so they are ignored unless
+// AllowForks is set.
+type Server struct {
+	AllowForks bool
+
+	logger *slog.Logger
+	secret []byte
+	org    string
+	scan   ScanFunc
+	queue  chan Event
+
+	mu      sync.Mutex
+	pending map[string]bool
+}
+
+func NewServer(logger *slog.Logger, secret []byte, org string, queueSize int, scan ScanFunc) *Server {
+	return &Server{
+		logger:  logger,
+		secret:  secret,
+		org:     org,
+		scan:    scan,
+		queue:   make(chan Event, queueSize),
+		pending: make(map[string]bool),
+	}
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	// the body is read in full before its signature can be checked
+	r.Body = http.MaxBytesReader(w, r.Body, MaxPayload)
+	payload, err := github.ValidatePayload(r, s.secret)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "payload too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		s.logger.Warn("rejected webhook", slog.String("delivery", github.DeliveryID(r)), slog.Any("error", err))
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+	eventType := github.WebHookType(r)
+	raw, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		http.Error(w, "unsupported event", http.StatusBadRequest)
+		return
+	}
+	ev, ok := s.toEvent(raw)
+	if !ok {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	if !s.enqueue(ev) {
+		http.Error(w, "scan queue full", http.StatusServiceUnavailable)
+		return
+	}
+	s.logger.Info("scan queued", slog.String("repo", ev.Repo), slog.String("sha", ev.SHA), slog.String("event", ev.Kind))
+	w.WriteHeader(http.StatusAccepted)
+}
+
+func (s *Server) toEvent(raw interface{}) (Event, bool) {
+	var ev Event
+	var owner string
+	switch e := raw.(type) {
+	case *github.PushEvent:
+		if e.GetDeleted() {
+			return ev, false
+		}
+		owner = e.GetRepo().GetOwner().GetLogin()
+		ev = Event{Repo: e.GetRepo().GetName(), SHA: e.GetAfter(), Ref: e.GetRef(), Kind: "push"}
+	case *github.PullRequestEvent:
+		switch e.GetAction() {
+		case "opened", "synchronize", "reopened":
+		default:
+			return ev, false
+		}
+		if head := e.GetPullRequest().GetHead().GetRepo().GetFullName(); !strings.EqualFold(head, e.GetRepo().GetFullName()) && !s.AllowForks {
+			s.logger.Info("ignoring pull request from a fork", slog.String("repo", e.GetRepo().GetName()), slog.String("head", head), slog.Int("number", e.GetNumber()))
+			return ev, false
+		}
+		owner = e.GetRepo().GetOwner().GetLogin()
+		ev = Event{Repo: e.GetRepo().GetName(), SHA: e.GetPullRequest().GetHead().GetSHA(), Ref: fmt.Sprintf("refs/pull/%d/head", e.GetNumber()), Kind: "pull_request"}
+	default:
+		return ev, false
+	}
+	if ev.Repo == "" || !strings.EqualFold(owner, s.org) {
+		return ev, false
+	}
+	return ev, true
+}
+
+// enqueue adds ev to the queue unless an identical scan is already waiting.
+func (s *Server) enqueue(ev Event) bool {
+	key := ev.Repo + "@" + ev.SHA
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.pending[key] {
+		return true
+	}
+	select {
+	case s.queue <- ev:
+		s.pending[key] = true
+		return true
+	default:
+		return false
+	}
+}
+
+// Work processes queued events until ctx is cancelled.
+func (s *Server) Work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case ev := <-s.queue:
+			s.mu.Lock()
+			delete(s.pending, ev.Repo+"@"+ev.SHA)
+			s.mu.Unlock()
+			if err := s.scan(ctx, ev); err != nil {
+				s.logger.Error("scan failed", slog.String("repo", ev.Repo), slog.String("sha", ev.SHA), slog.Any("error", fmt.Errorf("%s event: %w", ev.Kind, err)))
+			}
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+const pushPayload = `{"ref":"refs/heads/main","after":"abc123","repository":{"name":"app","owner":{"login":"acme"}}}`
+
+func sign(secret, body string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(body))
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func post(t *testing.T, h http.Handler, event, body, signature string) int {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", event)
+	req.Header.Set("X-Hub-Signature-256", signature)
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestServerRejectsBadSignature(t *testing.T) {
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "acme", 1, nil)
+	if code := post(t, srv, "push", pushPayload, sign("wrong", pushPayload)); code != http.StatusUnauthorized {
+		t.Fatalf("expected 401, got %d", code)
+	}
+}
+
+func TestServerQueuesPush(t *testing.T) {
+	got := make(chan Event, 1)
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "acme", 2, func(ctx context.Context, ev Event) error {
+		got <- ev
+		return nil
+	})
+	if code := post(t, srv, "push", pushPayload, sign("secret", pushPayload)); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	// duplicate deliveries for the same commit are coalesced
+	post(t, srv, "push", pushPayload, sign("secret", pushPayload))
+	if len(srv.queue) != 1 {
+		t.Fatalf("expected 1 queued event, got %d", len(srv.queue))
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go srv.Work(ctx)
+	select {
+	case ev := <-got:
+		if ev.Repo != "app" || ev.SHA != "abc123" || ev.Ref != "refs/heads/main" || ev.Kind != "push" {
+			t.Fatalf("unexpected event: %+v", ev)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("event not processed")
+	}
+}
+
+func TestServerIgnoresOtherOrgs(t *testing.T) {
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "other", 1, nil)
+	if code := post(t, srv, "push", pushPayload, sign("secret", pushPayload)); code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", code)
+	}
+}
+
+func prPayload(head string) string {
+	return `{"action":"synchronize","number":7,"pull_request":{"head":{"sha":"def456","repo":{"full_name":"` + head + `"}}},` +
+		`"repository":{"name":"app","full_name":"acme/app","owner":{"login":"acme"}}}`
+}
+
+func TestServerQueuesPullRequestHead(t *testing.T) {
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "acme", 1, nil)
+	body := prPayload("acme/app")
+	if code := post(t, srv, "pull_request", body, sign("secret", body)); code != http.StatusAccepted {
+		t.Fatalf("expected 202, got %d", code)
+	}
+	if ev := <-srv.queue; ev.SHA != "def456" || ev.Ref != "refs/pull/7/head" || ev.Kind != "pull_request" {
+		t.Fatalf("unexpected event: %+v", ev)
+	}
+}
+
+func TestServerIgnoresForkPullRequests(t *testing.T) {
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "acme", 1, nil)
+	body := prPayload("mallory/app")
+	if code := post(t, srv, "pull_request", body, sign("secret", body)); code != http.StatusNoContent {
+		t.Fatalf("expected 204 for a fork, got %d", code)
+	}
+	srv.AllowForks = true
+	if code := post(t, srv, "pull_request", body, sign("secret", body)); code != http.StatusAccepted {
+		t.Fatalf("expected 202 once forks are allowed, got %d", code)
+	}
+}
+
+func TestServerRejectsOversizedPayload(t *testing.T) {
+	srv := NewServer(slog.New(slog.NewTextHandler(io.Discard, nil)), []byte("secret"), "acme", 1, nil)
+	body := `{"ref":"` + strings.Repeat("a", MaxPayload) + `"}`
+	if code := post(t, srv, "push", body, sign("secret", body)); code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected 413, got %d", code)
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"os"
 
 	"github.com/cybrota/eskimo/cmd"
+	"github.com/cybrota/eskimo/internal/sandbox"
 )
 
 func main() {
+	sandbox.HandleChild()
 	if err := cmd.Execute(); err != nil {
 		os.Exit(1)
 	}
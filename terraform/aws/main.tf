@@ -345,6 +345,29 @@ resource "aws_iam_role_policy_attachment" "secret_access" {
   policy_arn = aws_iam_policy.secret_access.arn
 }
 
+# Allow the task to upload scan results
+data "aws_iam_policy_document" "results_upload" {
+  count = var.results_bucket != "" ? 1 : 0
+  statement {
+    actions   = ["s3:PutObject"]
+    resources = ["arn:aws:s3:::${var.results_bucket}/*"]
+  }
+}
+
+resource "aws_iam_policy" "results_upload" {
+  count  = var.results_bucket != "" ? 1 : 0
+  name   = "eskimo-results-upload"
+  policy = data.aws_iam_policy_document.results_upload[0].json
+
+  tags = var.tags
+}
+
+resource "aws_iam_role_policy_attachment" "results_upload" {
+  count      = var.results_bucket != "" ? 1 : 0
+  role       = aws_iam_role.task.name
+  policy_arn = aws_iam_policy.results_upload[0].arn
+}
+
 # CloudWatch Log Group
 resource "aws_cloudwatch_log_group" "ecs" {
   name              = "/ecs/eskimo"
@@ -403,7 +426,10 @@ resource "aws_ecs_task_definition" "scan" {
       name      = "eskimo"
       image     = local.image
       essential = true
-      command   = ["--org", var.github_org, "--config", "/app/scanners.yaml"]
+      command   = concat(
+        ["--org", var.github_org, "--config", "/app/scanners.yaml"],
+        var.results_bucket != "" ? ["--s3-bucket", var.results_bucket, "--s3-region", var.region] : []
+      )
       logConfiguration = {
         logDriver = "awslogs"
         options = {
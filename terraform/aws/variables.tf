@@ -45,6 +45,12 @@ variable "image_tag" {
   default     = ""
 }
 
+variable "results_bucket" {
+  description = "Existing S3 bucket receiving scan reports and artifacts. If empty, results only go to CloudWatch logs"
+  type        = string
+  default     = ""
+}
+
 variable "tags" {
   description = "Common tags to apply to all resources"
   type        = map(string)